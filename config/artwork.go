@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ArtworkPolicy determines what happens to the artwork embedded into a profile's outputs.
+type ArtworkPolicy int
+
+const (
+	// ArtworkCopy embeds artwork as-is.
+	ArtworkCopy ArtworkPolicy = iota
+
+	// ArtworkJpeg re-encodes artwork as baseline JPEG, scaled down to the profile's maximum size.
+	ArtworkJpeg
+
+	// ArtworkStrip removes all artwork.
+	ArtworkStrip
+)
+
+// DefaultArtworkQuality is the JPEG quality used by profiles that don't specify one.
+const DefaultArtworkQuality = 90
+
+// GetArtworkQuality returns the JPEG quality that the profile re-encodes artwork with, from 1 to 100.
+func (p *OutputProfile) GetArtworkQuality() uint {
+	if p.ArtworkQuality == 0 {
+		return DefaultArtworkQuality
+	}
+
+	return p.ArtworkQuality
+}
+
+// IsArtworkValid returns whether the profile's artwork policy exists and its JPEG quality is at most 100.
+func (p *OutputProfile) IsArtworkValid() bool {
+	return p.ArtworkPolicy >= ArtworkCopy && p.ArtworkPolicy <= ArtworkStrip && p.ArtworkQuality <= 100
+}
+
+// KeepsArtwork returns whether the profile's outputs can have artwork.
+func (p *OutputProfile) KeepsArtwork() bool {
+	return p.OutputFormat.SupportsArtwork && p.ArtworkPolicy != ArtworkStrip
+}
+
+// ArtworkFingerprint returns a string that identifies how the profile encodes artwork, regardless of whether its format
+// supports artwork.
+// Returns an empty string for ArtworkCopy, which is how artwork was handled before artwork policies existed.
+func (p *OutputProfile) ArtworkFingerprint() string {
+	switch p.ArtworkPolicy {
+	case ArtworkJpeg:
+		return fmt.Sprintf("artjpeg:%d:%d", p.MaxArtworkSize, p.GetArtworkQuality())
+	case ArtworkStrip:
+		return "artstrip"
+	default:
+		return ""
+	}
+}
+
+// ArtworkArgs returns the FFmpeg output options that encode the artwork streams of the profile's outputs.
+// Formats that don't support artwork have their artwork removed.
+func (p *OutputProfile) ArtworkArgs() []string {
+	if !p.KeepsArtwork() {
+		return []string{"-vn"}
+	}
+
+	return p.ArtworkEncodeArgs()
+}
+
+// ArtworkEncodeArgs returns the FFmpeg output options that encode artwork streams according to the profile's artwork
+// policy, regardless of whether its format supports artwork.
+// Must not be used with ArtworkStrip.
+func (p *OutputProfile) ArtworkEncodeArgs() []string {
+	if p.ArtworkPolicy != ArtworkJpeg {
+		return []string{"-c:v", "copy"}
+	}
+
+	// FFmpeg's JPEG quality scale goes from 2 (best) to 31 (worst).
+	// The JPEG encoder only writes baseline JPEGs, which every player can decode.
+	qscale := 2 + math.Round(float64(100-p.GetArtworkQuality())*29/99)
+	args := []string{
+		"-c:v", "mjpeg",
+		"-q:v", strconv.Itoa(int(qscale)),
+		"-pix_fmt", "yuvj420p",
+	}
+
+	// Artwork is only ever scaled down, keeping its aspect ratio
+	if p.MaxArtworkSize > 0 {
+		size := strconv.Itoa(int(p.MaxArtworkSize))
+		args = append(args, "-filter:v", "scale=w='min(iw,"+size+")':h='min(ih,"+size+")':force_original_aspect_ratio=decrease:flags=lanczos")
+	}
+
+	return args
+}
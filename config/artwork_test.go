@@ -0,0 +1,83 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOutputProfileArtworkArgs(t *testing.T) {
+	mp3 := SupportedOutputFormats[0]
+
+	tests := []struct {
+		name string
+		prof OutputProfile
+		want []string
+	}{
+		{"copied", OutputProfile{OutputFormat: mp3}, []string{"-c:v", "copy"}},
+		{"stripped", OutputProfile{OutputFormat: mp3, ArtworkPolicy: ArtworkStrip}, []string{"-vn"}},
+		{"format without artwork", OutputProfile{OutputFormat: SupportedOutputFormats[3]}, []string{"-vn"}},
+		{
+			"JPEG at the default quality",
+			OutputProfile{OutputFormat: mp3, ArtworkPolicy: ArtworkJpeg},
+			[]string{"-c:v", "mjpeg", "-q:v", "5", "-pix_fmt", "yuvj420p"},
+		},
+		{
+			"JPEG at the best quality",
+			OutputProfile{OutputFormat: mp3, ArtworkPolicy: ArtworkJpeg, ArtworkQuality: 100},
+			[]string{"-c:v", "mjpeg", "-q:v", "2", "-pix_fmt", "yuvj420p"},
+		},
+		{
+			"scaled JPEG at the worst quality",
+			OutputProfile{OutputFormat: mp3, ArtworkPolicy: ArtworkJpeg, ArtworkQuality: 1, MaxArtworkSize: 500},
+			[]string{
+				"-c:v", "mjpeg", "-q:v", "31", "-pix_fmt", "yuvj420p",
+				"-filter:v", "scale=w='min(iw,500)':h='min(ih,500)':force_original_aspect_ratio=decrease:flags=lanczos",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.prof.ArtworkArgs(); !slices.Equal(got, test.want) {
+			t.Errorf("%s: ArtworkArgs() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOutputProfileIsArtworkValid(t *testing.T) {
+	tests := []struct {
+		prof OutputProfile
+		want bool
+	}{
+		{OutputProfile{}, true},
+		{OutputProfile{ArtworkPolicy: ArtworkJpeg, ArtworkQuality: 100}, true},
+		{OutputProfile{ArtworkPolicy: ArtworkStrip}, true},
+		{OutputProfile{ArtworkPolicy: ArtworkJpeg, ArtworkQuality: 101}, false},
+		{OutputProfile{ArtworkPolicy: ArtworkStrip + 1}, false},
+		{OutputProfile{ArtworkPolicy: -1}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.prof.IsArtworkValid(); got != test.want {
+			t.Errorf("IsArtworkValid() with policy %d and quality %d = %v, want %v",
+				test.prof.ArtworkPolicy, test.prof.ArtworkQuality, got, test.want)
+		}
+	}
+}
+
+func TestOutputProfileArtworkFingerprint(t *testing.T) {
+	tests := []struct {
+		prof OutputProfile
+		want string
+	}{
+		{OutputProfile{}, ""},
+		{OutputProfile{ArtworkPolicy: ArtworkStrip}, "artstrip"},
+		{OutputProfile{ArtworkPolicy: ArtworkJpeg}, "artjpeg:0:90"},
+		{OutputProfile{ArtworkPolicy: ArtworkJpeg, MaxArtworkSize: 600, ArtworkQuality: 75}, "artjpeg:600:75"},
+	}
+
+	for _, test := range tests {
+		if got := test.prof.ArtworkFingerprint(); got != test.want {
+			t.Errorf("ArtworkFingerprint() of %+v = %q, want %q", test.prof, got, test.want)
+		}
+	}
+}
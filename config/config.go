@@ -1,9 +1,13 @@
 package config
 
 import (
+	"errors"
 	"github.com/termermc/your-loss-sync/lang"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 )
 
 // DirName is the name of the configuration directory.
@@ -33,6 +37,39 @@ func GetFilePath() (string, error) {
 	return filepath.Join(cfgDir, FileName), nil
 }
 
+// FilePolicy determines which kinds of source files a sync copies to the destination.
+type FilePolicy int
+
+const (
+	// FilePolicyCopyAll syncs every file, copying files that aren't audio as-is.
+	FilePolicyCopyAll FilePolicy = iota
+
+	// FilePolicyAudioOnly only syncs audio files.
+	FilePolicyAudioOnly
+
+	// FilePolicyAudioAndSidecars syncs audio files, plus files whose extensions are in SyncConfig.SidecarExtensions.
+	FilePolicyAudioAndSidecars
+)
+
+// IsValid returns whether the file policy exists.
+func (p FilePolicy) IsValid() bool {
+	return p >= FilePolicyCopyAll && p <= FilePolicyAudioAndSidecars
+}
+
+// DefaultSidecarExtensions are the extensions of cover images and lyrics, which are synced by default alongside audio
+// files when using FilePolicyAudioAndSidecars.
+var DefaultSidecarExtensions = []string{"jpg", "jpeg", "png", "webp", "lrc"}
+
+// DefaultArtworkFilenames are the names of folder images that are embedded as artwork by default, from most to least
+// preferred.
+var DefaultArtworkFilenames = []string{"cover.jpg", "cover.jpeg", "cover.png", "folder.jpg", "folder.jpeg", "folder.png", "front.jpg", "front.jpeg", "front.png"}
+
+// ErrInvalidArtworkFilename is returned when a folder image file name is not a JPEG or PNG file name.
+var ErrInvalidArtworkFilename = errors.New("{{config.error.invalid-artwork-filename}}")
+
+// artworkExtensions are the extensions of images that can be embedded as artwork in every format that supports artwork.
+var artworkExtensions = []string{".jpg", ".jpeg", ".png"}
+
 // SyncConfig is the configuration for a sync.
 type SyncConfig struct {
 	// The sync's name.
@@ -45,8 +82,19 @@ type SyncConfig struct {
 	DestDir string
 
 	// The output profile to use.
+	// Audio files that match one of ConversionRules are synced according to the rule instead.
 	Profile *OutputProfile
 
+	// Ordered rules that decide how audio files are synced based on their format.
+	// The first rule that matches a file is used, and files that don't match any rule use Profile.
+	// Default: none
+	ConversionRules []ConversionRule
+
+	// Ordered rules that rewrite the tags of synced audio files.
+	// Raw copies whose tags are changed by the rules are remuxed instead of copied.
+	// Default: none
+	TagRules []TagRule
+
 	// Whether to escape filenames.
 	// Default: true
 	EscapeFilenames bool
@@ -54,6 +102,83 @@ type SyncConfig struct {
 	// Whether to reencode files with the same format.
 	// Default: false
 	ReencodeSameFormat bool
+
+	// Whether to avoid transcoding lossy files when it would not save space.
+	// Lossy files are never converted to lossless formats, and are copied as-is if their bitrate is at or below the
+	// profile's bitrate, unless their codec may not be playable.
+	// Default: false
+	SmartTranscode bool
+
+	// Whether to delete files in the destination directory that no longer correspond to any file in the source directory.
+	// Default: false
+	Mirror bool
+
+	// The number of files to process at once.
+	// If 0, Config.DefaultConcurrency is used.
+	// Default: 0
+	Concurrency uint
+
+	// Whether to run FFmpeg at a lowered CPU and IO priority.
+	// Default: false
+	LowPriority bool
+
+	// Ordered rules that determine which source files are synced.
+	// See IsIncluded.
+	// Default: none, all files are synced
+	FilterRules []FilterRule
+
+	// Which kinds of files are synced.
+	// Default: FilePolicyCopyAll
+	FilePolicy FilePolicy
+
+	// The extensions of files that are synced alongside audio files when using FilePolicyAudioAndSidecars.
+	// Extensions are lowercase and don't include the leading dot.
+	// If nil, DefaultSidecarExtensions is used.
+	// Default: nil
+	SidecarExtensions []string
+
+	// Whether to embed a folder image, such as cover.jpg, as artwork into outputs whose source files have no embedded
+	// artwork.
+	// Only applies to formats that support artwork.
+	// Outputs are only updated when their source files change, not when the folder image does.
+	// Default: false
+	EmbedFolderArtwork bool
+
+	// The file names of folder images that can be embedded, from most to least preferred.
+	// Names are matched case-insensitively, and must be valid according to IsArtworkFilenameValid.
+	// If nil, DefaultArtworkFilenames is used.
+	// Default: nil
+	ArtworkFilenames []string
+}
+
+// GetSidecarExtensions returns the extensions of files that are synced alongside audio files when using
+// FilePolicyAudioAndSidecars.
+func (s *SyncConfig) GetSidecarExtensions() []string {
+	if s.SidecarExtensions == nil {
+		return DefaultSidecarExtensions
+	}
+
+	return s.SidecarExtensions
+}
+
+// GetArtworkFilenames returns the file names of folder images that can be embedded by the sync, from most to least
+// preferred.
+func (s *SyncConfig) GetArtworkFilenames() []string {
+	if s.ArtworkFilenames == nil {
+		return DefaultArtworkFilenames
+	}
+
+	return s.ArtworkFilenames
+}
+
+// IsArtworkFilenameValid returns whether a folder image file name can be used in SyncConfig.ArtworkFilenames.
+// It must be a file name rather than a path, and must be a JPEG or PNG image.
+func IsArtworkFilenameValid(name string) bool {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+
+	return slices.Contains(artworkExtensions, strings.ToLower(filepath.Ext(name)))
 }
 
 // Config is the application configuration.
@@ -64,8 +189,21 @@ type Config struct {
 	// All output profiles.
 	Profiles []*OutputProfile
 
+	// User-defined output formats, keyed by ID.
+	// IDs must be at least CustomFormatIdStart, and names must not be used by any other format.
+	// Default: nil
+	CustomFormats map[int]OutputFormat
+
 	// All sync configurations.
 	Syncs []*SyncConfig
+
+	// The number of files to process at once for syncs that don't specify their own concurrency.
+	// If 0, the number of CPU threads is used.
+	DefaultConcurrency uint
+
+	// How files that failed to sync are retried.
+	// Default: DefaultRetryPolicy
+	RetryPolicy RetryPolicy
 }
 
 // CreateDefault creates a default configuration.
@@ -74,6 +212,8 @@ func CreateDefault(locale lang.Locale) *Config {
 		LangCode: lang.DefaultLangCode,
 		Profiles: DefaultOutputProfiles,
 		Syncs:    []*SyncConfig{},
+
+		RetryPolicy: DefaultRetryPolicy(),
 	}
 
 	for i := range res.Profiles {
@@ -108,6 +248,20 @@ func (c *Config) GetProfileIndex(name string) int {
 	return -1
 }
 
+// GetConcurrency returns the number of files the specified sync should process at once.
+func (c *Config) GetConcurrency(sync *SyncConfig) int {
+	if sync.Concurrency > 0 {
+		return int(sync.Concurrency)
+	}
+
+	if c.DefaultConcurrency > 0 {
+		return int(c.DefaultConcurrency)
+	}
+
+	// Assume that transcoding a file maxes out a single CPU thread
+	return max(runtime.NumCPU(), 1)
+}
+
 // GetSync returns the sync with the specified name.
 // If no sync with the specified name exists, nil will be returned.
 func (c *Config) GetSync(name string) *SyncConfig {
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsArtworkFilenameValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"cover.jpg", true},
+		{"Folder.JPEG", true},
+		{"front.png", true},
+		{"cover.webp", false},
+		{"cover", false},
+		{"", false},
+		{"art/cover.jpg", false},
+		{`art\cover.jpg`, false},
+	}
+
+	for _, test := range tests {
+		if got := IsArtworkFilenameValid(test.name); got != test.valid {
+			t.Errorf("IsArtworkFilenameValid(%q) = %v, want %v", test.name, got, test.valid)
+		}
+	}
+
+	for _, name := range DefaultArtworkFilenames {
+		if !IsArtworkFilenameValid(name) {
+			t.Errorf("default folder image name %q is not valid", name)
+		}
+	}
+}
+
+func TestSyncConfigGetArtworkFilenames(t *testing.T) {
+	sync := &SyncConfig{}
+	if got := sync.GetArtworkFilenames(); !slices.Equal(got, DefaultArtworkFilenames) {
+		t.Errorf("GetArtworkFilenames() without names = %q, want the defaults", got)
+	}
+
+	// An empty list is kept rather than replaced by the defaults
+	sync.ArtworkFilenames = []string{}
+	if got := sync.GetArtworkFilenames(); len(got) != 0 {
+		t.Errorf("GetArtworkFilenames() with an empty list = %q, want none", got)
+	}
+}
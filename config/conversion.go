@@ -0,0 +1,290 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidConversionRule is returned when a conversion rule can't be parsed.
+var ErrInvalidConversionRule = errors.New("{{config.error.invalid-conversion-rule}}")
+
+// ErrUnknownConversionProfile is returned when a conversion rule refers to a profile that doesn't exist.
+var ErrUnknownConversionProfile = errors.New("{{config.error.unknown-conversion-profile}}")
+
+// ConversionAction is what a conversion rule does with the source files it matches.
+type ConversionAction int
+
+const (
+	// ConversionTranscode transcodes matching files with the rule's profile.
+	ConversionTranscode ConversionAction = iota
+
+	// ConversionCopy copies matching files as-is.
+	ConversionCopy
+
+	// ConversionSkip does not sync matching files.
+	ConversionSkip
+)
+
+// ConversionRule decides how source audio files that match it are synced, instead of the sync's profile.
+// All of a rule's conditions must match for the rule to match.
+type ConversionRule struct {
+	// The FFprobe codec names to match.
+	// If empty, any codec matches.
+	Codecs []string
+
+	// The file extensions to match, lowercase and without the leading dot.
+	// If empty, any extension matches.
+	Extensions []string
+
+	// The minimum sample rate to match, in Hz.
+	// If 0, there is no minimum.
+	MinSampleRate uint
+
+	// The maximum sample rate to match, in Hz.
+	// If 0, there is no maximum.
+	MaxSampleRate uint
+
+	// The minimum bit depth to match.
+	// Lossy files have no bit depth, so they never match a rule with a bit depth condition.
+	// If 0, there is no minimum.
+	MinBitDepth uint
+
+	// The maximum bit depth to match.
+	// If 0, there is no maximum.
+	MaxBitDepth uint
+
+	// What to do with matching files.
+	Action ConversionAction
+
+	// The profile to transcode matching files with.
+	// Only set for ConversionTranscode.
+	Profile *OutputProfile
+}
+
+// Matches returns whether the rule matches a source file with the specified properties.
+// The extension must be lowercase and without the leading dot.
+// The sample rate and bit depth are 0 if they are unknown.
+func (r *ConversionRule) Matches(codec string, ext string, sampleRate uint, bitDepth uint) bool {
+	if len(r.Codecs) > 0 && !slices.Contains(r.Codecs, codec) {
+		return false
+	}
+	if len(r.Extensions) > 0 && !slices.Contains(r.Extensions, ext) {
+		return false
+	}
+
+	if (r.MinSampleRate > 0 || r.MaxSampleRate > 0) && sampleRate == 0 {
+		return false
+	}
+	if r.MinSampleRate > 0 && sampleRate < r.MinSampleRate {
+		return false
+	}
+	if r.MaxSampleRate > 0 && sampleRate > r.MaxSampleRate {
+		return false
+	}
+
+	if (r.MinBitDepth > 0 || r.MaxBitDepth > 0) && bitDepth == 0 {
+		return false
+	}
+	if r.MinBitDepth > 0 && bitDepth < r.MinBitDepth {
+		return false
+	}
+	if r.MaxBitDepth > 0 && bitDepth > r.MaxBitDepth {
+		return false
+	}
+
+	return true
+}
+
+// conditionsString returns the rule's conditions in the form accepted by ParseConversionRule.
+func (r *ConversionRule) conditionsString() string {
+	var conditions []string
+	if len(r.Codecs) > 0 {
+		conditions = append(conditions, "codec="+strings.Join(r.Codecs, ","))
+	}
+	if len(r.Extensions) > 0 {
+		conditions = append(conditions, "ext="+strings.Join(r.Extensions, ","))
+	}
+	if r.MinSampleRate > 0 {
+		conditions = append(conditions, "rate>="+strconv.Itoa(int(r.MinSampleRate)))
+	}
+	if r.MaxSampleRate > 0 {
+		conditions = append(conditions, "rate<="+strconv.Itoa(int(r.MaxSampleRate)))
+	}
+	if r.MinBitDepth > 0 {
+		conditions = append(conditions, "depth>="+strconv.Itoa(int(r.MinBitDepth)))
+	}
+	if r.MaxBitDepth > 0 {
+		conditions = append(conditions, "depth<="+strconv.Itoa(int(r.MaxBitDepth)))
+	}
+
+	if len(conditions) == 0 {
+		return "*"
+	}
+
+	return strings.Join(conditions, " ")
+}
+
+// String returns the rule in the form accepted by ParseConversionRule.
+func (r *ConversionRule) String() string {
+	switch r.Action {
+	case ConversionCopy:
+		return r.conditionsString() + " => copy"
+	case ConversionSkip:
+		return r.conditionsString() + " => skip"
+	case ConversionTranscode:
+		return r.conditionsString() + " => profile " + r.Profile.Name
+	default:
+		// Rejected by ParseConversionRule, like any other unknown action
+		return r.conditionsString() + " => invalid"
+	}
+}
+
+// Fingerprint returns a string that identifies the rule's conditions and the output it produces.
+// Unlike String, it does not depend on the name of the rule's profile.
+func (r *ConversionRule) Fingerprint() string {
+	switch r.Action {
+	case ConversionCopy:
+		return r.conditionsString() + "=copy"
+	case ConversionSkip:
+		return r.conditionsString() + "=skip"
+	case ConversionTranscode:
+		return r.conditionsString() + "=" + r.Profile.Fingerprint()
+	default:
+		return r.conditionsString() + "=invalid"
+	}
+}
+
+// Validate checks that the rule has a known action, and that a transcoding rule has a profile.
+// If the action is unknown, ErrInvalidConversionRule is returned.
+// If the rule transcodes but has no profile, ErrUnknownConversionProfile is returned.
+func (r *ConversionRule) Validate() error {
+	switch r.Action {
+	case ConversionCopy, ConversionSkip:
+		return nil
+	case ConversionTranscode:
+		if r.Profile == nil {
+			return ErrUnknownConversionProfile
+		}
+		return nil
+	default:
+		return ErrInvalidConversionRule
+	}
+}
+
+// ParseConversionRule parses a conversion rule in the form "conditions => action".
+// Conditions are separated by spaces, and can be "codec=name,...", "ext=extension,...", "rate>=hz", "rate<=hz",
+// "depth>=bits" or "depth<=bits". A single "*" matches every file.
+// The action can be "copy", "skip" or "profile <profile name>".
+// If the rule is malformed, ErrInvalidConversionRule is returned.
+// If the rule's profile does not exist in the config, ErrUnknownConversionProfile is returned.
+func ParseConversionRule(str string, cfg *Config) (ConversionRule, error) {
+	conditionsStr, actionStr, ok := strings.Cut(str, "=>")
+	if !ok {
+		return ConversionRule{}, ErrInvalidConversionRule
+	}
+
+	var rule ConversionRule
+
+	conditions := strings.Fields(conditionsStr)
+	if len(conditions) == 0 {
+		return ConversionRule{}, ErrInvalidConversionRule
+	}
+	if len(conditions) > 1 || conditions[0] != "*" {
+		for _, condition := range conditions {
+			if !rule.parseCondition(condition) {
+				return ConversionRule{}, ErrInvalidConversionRule
+			}
+		}
+	}
+
+	actionStr = strings.TrimSpace(actionStr)
+	switch {
+	case actionStr == "copy":
+		rule.Action = ConversionCopy
+	case actionStr == "skip":
+		rule.Action = ConversionSkip
+	case strings.HasPrefix(actionStr, "profile "):
+		rule.Action = ConversionTranscode
+		rule.Profile = cfg.GetProfile(strings.TrimSpace(strings.TrimPrefix(actionStr, "profile ")))
+	default:
+		return ConversionRule{}, ErrInvalidConversionRule
+	}
+
+	if err := rule.Validate(); err != nil {
+		return ConversionRule{}, err
+	}
+
+	return rule, nil
+}
+
+// parseCondition parses a single condition into the rule.
+// Returns false if the condition is malformed.
+func (r *ConversionRule) parseCondition(condition string) bool {
+	parseList := func(value string) []string {
+		var res []string
+		for _, item := range strings.Split(value, ",") {
+			item = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(item), "."))
+			if item != "" {
+				res = append(res, item)
+			}
+		}
+		return res
+	}
+
+	parseNum := func(value string) (uint, bool) {
+		num, err := strconv.ParseUint(value, 10, 0)
+		return uint(num), err == nil && num > 0
+	}
+
+	var ok bool
+	switch {
+	case strings.HasPrefix(condition, "codec="):
+		r.Codecs = parseList(strings.TrimPrefix(condition, "codec="))
+		ok = len(r.Codecs) > 0
+	case strings.HasPrefix(condition, "ext="):
+		r.Extensions = parseList(strings.TrimPrefix(condition, "ext="))
+		ok = len(r.Extensions) > 0
+	case strings.HasPrefix(condition, "rate>="):
+		r.MinSampleRate, ok = parseNum(strings.TrimPrefix(condition, "rate>="))
+	case strings.HasPrefix(condition, "rate<="):
+		r.MaxSampleRate, ok = parseNum(strings.TrimPrefix(condition, "rate<="))
+	case strings.HasPrefix(condition, "depth>="):
+		r.MinBitDepth, ok = parseNum(strings.TrimPrefix(condition, "depth>="))
+	case strings.HasPrefix(condition, "depth<="):
+		r.MaxBitDepth, ok = parseNum(strings.TrimPrefix(condition, "depth<="))
+	}
+
+	return ok
+}
+
+// GetConversionRule returns the first of the sync's conversion rules that matches a source file with the specified
+// properties, or nil if none match.
+// See ConversionRule.Matches.
+func (s *SyncConfig) GetConversionRule(codec string, ext string, sampleRate uint, bitDepth uint) *ConversionRule {
+	for i := range s.ConversionRules {
+		if s.ConversionRules[i].Matches(codec, ext, sampleRate, bitDepth) {
+			return &s.ConversionRules[i]
+		}
+	}
+
+	return nil
+}
+
+// IsProfileInUse returns whether the profile with the specified name is used by any sync or conversion rule.
+func (c *Config) IsProfileInUse(name string) bool {
+	for _, sync := range c.Syncs {
+		if sync.Profile.Name == name {
+			return true
+		}
+
+		for _, rule := range sync.ConversionRules {
+			if rule.Profile != nil && rule.Profile.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
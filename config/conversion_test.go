@@ -0,0 +1,182 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+// newConversionTestConfig returns a config with a FLAC and an MP3 profile.
+func newConversionTestConfig() *Config {
+	return &Config{Profiles: []*OutputProfile{
+		{Name: "FLAC 16", OutputFormat: SupportedOutputFormats[1], Quality: 5, MaxBitDepth: 16},
+		{Name: "Phone MP3", OutputFormat: SupportedOutputFormats[0], Bitrate: 192000},
+	}}
+}
+
+func TestParseConversionRule(t *testing.T) {
+	cfg := newConversionTestConfig()
+
+	tests := []struct {
+		str  string
+		want ConversionRule
+	}{
+		{"codec=flac => profile Phone MP3", ConversionRule{Codecs: []string{"flac"}, Action: ConversionTranscode, Profile: cfg.Profiles[1]}},
+		{"codec=FLAC,alac ext=.FLAC,m4a => copy", ConversionRule{Codecs: []string{"flac", "alac"}, Extensions: []string{"flac", "m4a"}, Action: ConversionCopy}},
+		{"rate>=88200 depth>=24 =>   profile   FLAC 16  ", ConversionRule{MinSampleRate: 88200, MinBitDepth: 24, Action: ConversionTranscode, Profile: cfg.Profiles[0]}},
+		{"rate<=48000 depth<=16 => skip", ConversionRule{MaxSampleRate: 48000, MaxBitDepth: 16, Action: ConversionSkip}},
+		{"* => copy", ConversionRule{Action: ConversionCopy}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseConversionRule(test.str, cfg)
+		if err != nil {
+			t.Errorf("ParseConversionRule(%q) error = %v", test.str, err)
+			continue
+		}
+
+		if !slices.Equal(got.Codecs, test.want.Codecs) || !slices.Equal(got.Extensions, test.want.Extensions) ||
+			got.MinSampleRate != test.want.MinSampleRate || got.MaxSampleRate != test.want.MaxSampleRate ||
+			got.MinBitDepth != test.want.MinBitDepth || got.MaxBitDepth != test.want.MaxBitDepth ||
+			got.Action != test.want.Action || got.Profile != test.want.Profile {
+			t.Errorf("ParseConversionRule(%q) = %+v, want %+v", test.str, got, test.want)
+		}
+
+		reparsed, err := ParseConversionRule(got.String(), cfg)
+		if err != nil || reparsed.String() != got.String() {
+			t.Errorf("ParseConversionRule(%q) does not round-trip through String %q: %v", test.str, got.String(), err)
+		}
+	}
+}
+
+func TestParseConversionRuleInvalid(t *testing.T) {
+	cfg := newConversionTestConfig()
+
+	tests := []struct {
+		str  string
+		want error
+	}{
+		{"", ErrInvalidConversionRule},
+		{"codec=flac", ErrInvalidConversionRule},
+		{"=> copy", ErrInvalidConversionRule},
+		{"codec= => copy", ErrInvalidConversionRule},
+		{"codec=flac => convert", ErrInvalidConversionRule},
+		{"codec=flac => profile", ErrInvalidConversionRule},
+		{"codec=flac => invalid", ErrInvalidConversionRule},
+		{"rate>=0 => copy", ErrInvalidConversionRule},
+		{"rate>44100 => copy", ErrInvalidConversionRule},
+		{"depth<=-16 => copy", ErrInvalidConversionRule},
+		{"bitrate>=320000 => copy", ErrInvalidConversionRule},
+		{"* codec=flac => copy", ErrInvalidConversionRule},
+		{"codec=flac => profile Missing", ErrUnknownConversionProfile},
+	}
+
+	for _, test := range tests {
+		if rule, err := ParseConversionRule(test.str, cfg); err != test.want {
+			t.Errorf("ParseConversionRule(%q) = %+v, %v, want %v", test.str, rule, err, test.want)
+		}
+	}
+}
+
+func TestConversionRuleValidate(t *testing.T) {
+	prof := newConversionTestConfig().Profiles[0]
+
+	tests := []struct {
+		rule ConversionRule
+		want error
+	}{
+		{ConversionRule{Action: ConversionTranscode, Profile: prof}, nil},
+		{ConversionRule{Action: ConversionCopy}, nil},
+		{ConversionRule{Action: ConversionSkip}, nil},
+		{ConversionRule{Action: ConversionTranscode}, ErrUnknownConversionProfile},
+		{ConversionRule{Action: ConversionAction(3), Profile: prof}, ErrInvalidConversionRule},
+		{ConversionRule{Action: ConversionAction(-1)}, ErrInvalidConversionRule},
+	}
+
+	for _, test := range tests {
+		if err := test.rule.Validate(); err != test.want {
+			t.Errorf("Validate(%+v) = %v, want %v", test.rule, err, test.want)
+		}
+	}
+}
+
+func TestConversionRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule       ConversionRule
+		codec      string
+		ext        string
+		sampleRate uint
+		bitDepth   uint
+		want       bool
+	}{
+		{ConversionRule{}, "mp3", "mp3", 0, 0, true},
+		{ConversionRule{Codecs: []string{"flac", "alac"}}, "alac", "m4a", 44100, 16, true},
+		{ConversionRule{Codecs: []string{"flac", "alac"}}, "aac", "m4a", 44100, 0, false},
+		{ConversionRule{Extensions: []string{"wav"}}, "pcm_s24le", "wav", 96000, 24, true},
+		{ConversionRule{Extensions: []string{"wav"}}, "pcm_s24be", "aif", 96000, 24, false},
+		{ConversionRule{MinSampleRate: 88200}, "flac", "flac", 96000, 24, true},
+		{ConversionRule{MinSampleRate: 88200}, "flac", "flac", 88200, 24, true},
+		{ConversionRule{MinSampleRate: 88200}, "flac", "flac", 44100, 16, false},
+		{ConversionRule{MinSampleRate: 88200}, "flac", "flac", 0, 16, false},
+		{ConversionRule{MaxSampleRate: 48000}, "mp3", "mp3", 44100, 0, true},
+		{ConversionRule{MaxSampleRate: 48000}, "mp3", "mp3", 0, 0, false},
+		{ConversionRule{MinBitDepth: 24}, "flac", "flac", 44100, 24, true},
+		{ConversionRule{MinBitDepth: 24}, "flac", "flac", 44100, 16, false},
+		{ConversionRule{MaxBitDepth: 16}, "flac", "flac", 44100, 16, true},
+
+		// Lossy files have no bit depth
+		{ConversionRule{MaxBitDepth: 16}, "mp3", "mp3", 44100, 0, false},
+
+		{ConversionRule{Codecs: []string{"flac"}, MinSampleRate: 88200, MinBitDepth: 24}, "flac", "flac", 96000, 16, false},
+		{ConversionRule{Codecs: []string{"flac"}, MinSampleRate: 88200, MinBitDepth: 24}, "flac", "flac", 96000, 24, true},
+	}
+
+	for _, test := range tests {
+		if got := test.rule.Matches(test.codec, test.ext, test.sampleRate, test.bitDepth); got != test.want {
+			t.Errorf("%q.Matches(%q, %q, %d, %d) = %v, want %v", test.rule.conditionsString(), test.codec, test.ext,
+				test.sampleRate, test.bitDepth, got, test.want)
+		}
+	}
+}
+
+func TestGetConversionRule(t *testing.T) {
+	sync := &SyncConfig{ConversionRules: []ConversionRule{
+		{Codecs: []string{"flac"}, MinSampleRate: 88200, Action: ConversionSkip},
+		{Codecs: []string{"flac"}, Action: ConversionCopy},
+	}}
+
+	// The first matching rule applies
+	if rule := sync.GetConversionRule("flac", "flac", 96000, 24); rule != &sync.ConversionRules[0] {
+		t.Errorf("GetConversionRule() for hi-res FLAC = %+v, want the first rule", rule)
+	}
+	if rule := sync.GetConversionRule("flac", "flac", 44100, 16); rule != &sync.ConversionRules[1] {
+		t.Errorf("GetConversionRule() for CD FLAC = %+v, want the second rule", rule)
+	}
+	if rule := sync.GetConversionRule("mp3", "mp3", 44100, 0); rule != nil {
+		t.Errorf("GetConversionRule() for MP3 = %+v, want no rule", rule)
+	}
+}
+
+func TestConversionRuleFingerprint(t *testing.T) {
+	cfg := newConversionTestConfig()
+	rule := ConversionRule{Codecs: []string{"flac"}, Action: ConversionTranscode, Profile: cfg.Profiles[0]}
+
+	// Renaming the profile doesn't change the output
+	before := rule.Fingerprint()
+	renamed := *cfg.Profiles[0]
+	renamed.Name = "Renamed"
+	rule.Profile = &renamed
+	if rule.Fingerprint() != before {
+		t.Errorf("Fingerprint() changed after renaming the profile: %q, was %q", rule.Fingerprint(), before)
+	}
+
+	rule.Profile = cfg.Profiles[1]
+	if rule.Fingerprint() == before {
+		t.Error("Fingerprint() did not change with the profile")
+	}
+
+	copyRule := ConversionRule{Codecs: []string{"flac"}, Action: ConversionCopy}
+	skipRule := ConversionRule{Codecs: []string{"flac"}, Action: ConversionSkip}
+	if copyRule.Fingerprint() == skipRule.Fingerprint() {
+		t.Error("copy and skip rules have the same fingerprint")
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// CustomFormatIdStart is the smallest ID of a custom output format.
+// Built-in formats use smaller IDs, so that formats added in later versions never collide with custom ones.
+const CustomFormatIdStart = 1000
+
+// ErrInvalidCustomFormat is returned when a custom output format has an invalid ID, is missing required fields, or has
+// the same name as another format.
+var ErrInvalidCustomFormat = errors.New("{{config.error.invalid-custom-format}}")
+
+// GetOutputFormats returns all output formats, including custom formats, keyed by ID.
+func (c *Config) GetOutputFormats() map[int]OutputFormat {
+	res := make(map[int]OutputFormat, len(SupportedOutputFormats)+len(c.CustomFormats))
+	for id, format := range SupportedOutputFormats {
+		res[id] = format
+	}
+	for id, format := range c.CustomFormats {
+		res[id] = format
+	}
+
+	return res
+}
+
+// GetOutputFormat returns the output format with the specified name, including custom formats.
+// If no format with the specified name exists, nil will be returned.
+func (c *Config) GetOutputFormat(name string) *OutputFormat {
+	if format := GetOutputFormat(name); format != nil {
+		return format
+	}
+
+	for _, format := range c.CustomFormats {
+		if format.Name == name {
+			return &format
+		}
+	}
+
+	return nil
+}
+
+// GetOutputFormatId returns the ID of the format, including custom formats.
+// Formats are identified by their names.
+// If the format does not exist, the ID will be 0 and false will be returned.
+func (c *Config) GetOutputFormatId(format OutputFormat) (int, bool) {
+	if id, ok := format.GetId(); ok {
+		return id, true
+	}
+
+	for id, customFormat := range c.CustomFormats {
+		if customFormat.Name == format.Name {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// ValidateCustomFormat checks that a custom output format with the specified ID can be added to the config.
+// The ID must be at least CustomFormatIdStart, the name, extension and encoder must be set, lossy formats must have a
+// suggested bitrate, the tag style must exist, and no other format may have the same name.
+// If the format is not valid, ErrInvalidCustomFormat is returned.
+func (c *Config) ValidateCustomFormat(id int, format OutputFormat) error {
+	if id < CustomFormatIdStart {
+		return ErrInvalidCustomFormat
+	}
+
+	if format.Name == "" || format.FfmpegEncoder == "" || strings.HasPrefix(format.FfmpegEncoder, "-") {
+		return ErrInvalidCustomFormat
+	}
+	if format.Extension == "" || strings.ContainsAny(format.Extension, `./\`) {
+		return ErrInvalidCustomFormat
+	}
+	if !format.IsLossless && format.SuggestedBitrate == 0 {
+		return ErrInvalidCustomFormat
+	}
+	if format.TagStyle < TagStyleFfmpeg || format.TagStyle > TagStyleRiffInfo {
+		return ErrInvalidCustomFormat
+	}
+
+	if GetOutputFormat(format.Name) != nil {
+		return ErrInvalidCustomFormat
+	}
+	for otherId, other := range c.CustomFormats {
+		if otherId != id && other.Name == format.Name {
+			return ErrInvalidCustomFormat
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import "testing"
+
+// newCustomFormat returns a valid lossy custom format.
+func newCustomFormat(name string) OutputFormat {
+	return OutputFormat{
+		Name:             name,
+		Extension:        "mp2",
+		FfmpegEncoder:    "mp2",
+		TagStyle:         TagStyleId3,
+		SuggestedBitrate: 256000,
+	}
+}
+
+func TestValidateCustomFormat(t *testing.T) {
+	cfg := &Config{CustomFormats: map[int]OutputFormat{1000: newCustomFormat("MP2")}}
+
+	tests := []struct {
+		name   string
+		id     int
+		modify func(format *OutputFormat)
+		valid  bool
+	}{
+		{"valid", 1001, func(format *OutputFormat) {}, true},
+		{"lossless without a bitrate", 1001, func(format *OutputFormat) { format.IsLossless = true; format.SuggestedBitrate = 0 }, true},
+		{"replacing itself", 1000, func(format *OutputFormat) { format.Name = "MP2" }, true},
+		{"built-in ID", 8, func(format *OutputFormat) {}, false},
+		{"ID reserved for built-in formats", CustomFormatIdStart - 1, func(format *OutputFormat) {}, false},
+		{"negative ID", -1, func(format *OutputFormat) {}, false},
+		{"missing name", 1001, func(format *OutputFormat) { format.Name = "" }, false},
+		{"missing encoder", 1001, func(format *OutputFormat) { format.FfmpegEncoder = "" }, false},
+		{"encoder that is an option", 1001, func(format *OutputFormat) { format.FfmpegEncoder = "-y" }, false},
+		{"missing extension", 1001, func(format *OutputFormat) { format.Extension = "" }, false},
+		{"extension with a dot", 1001, func(format *OutputFormat) { format.Extension = ".mp2" }, false},
+		{"extension with a path", 1001, func(format *OutputFormat) { format.Extension = "../mp2" }, false},
+		{"lossy without a bitrate", 1001, func(format *OutputFormat) { format.SuggestedBitrate = 0 }, false},
+		{"unknown tag style", 1001, func(format *OutputFormat) { format.TagStyle = TagStyleRiffInfo + 1 }, false},
+		{"name of a built-in format", 1001, func(format *OutputFormat) { format.Name = "FLAC" }, false},
+		{"name of another custom format", 1001, func(format *OutputFormat) { format.Name = "MP2" }, false},
+	}
+
+	for _, test := range tests {
+		format := newCustomFormat("Custom")
+		test.modify(&format)
+
+		if err := cfg.ValidateCustomFormat(test.id, format); (err == nil) != test.valid {
+			t.Errorf("%s: ValidateCustomFormat() error = %v, want valid = %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestConfigGetOutputFormats(t *testing.T) {
+	cfg := &Config{CustomFormats: map[int]OutputFormat{1000: newCustomFormat("MP2")}}
+
+	formats := cfg.GetOutputFormats()
+	if len(formats) != len(SupportedOutputFormats)+1 || formats[1000].Name != "MP2" || formats[1].Name != "FLAC" {
+		t.Errorf("GetOutputFormats() = %v", formats)
+	}
+
+	if format := cfg.GetOutputFormat("MP2"); format == nil || format.Extension != "mp2" {
+		t.Errorf("GetOutputFormat(\"MP2\") = %v", format)
+	}
+	if format := cfg.GetOutputFormat("FLAC"); format == nil || format.Extension != "flac" {
+		t.Errorf("GetOutputFormat(\"FLAC\") = %v", format)
+	}
+	if format := cfg.GetOutputFormat("Missing"); format != nil {
+		t.Errorf("GetOutputFormat(\"Missing\") = %v, want nil", format)
+	}
+
+	if id, ok := cfg.GetOutputFormatId(newCustomFormat("MP2")); !ok || id != 1000 {
+		t.Errorf("GetOutputFormatId() of a custom format = %d, %v", id, ok)
+	}
+	if id, ok := cfg.GetOutputFormatId(SupportedOutputFormats[1]); !ok || id != 1 {
+		t.Errorf("GetOutputFormatId() of a built-in format = %d, %v", id, ok)
+	}
+	if _, ok := cfg.GetOutputFormatId(newCustomFormat("Missing")); ok {
+		t.Error("GetOutputFormatId() of an unknown format succeeded")
+	}
+}
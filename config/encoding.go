@@ -0,0 +1,90 @@
+package config
+
+import (
+	"math"
+	"slices"
+)
+
+// EncodingMode is how an encoder decides how many bits to spend on audio.
+type EncodingMode int
+
+const (
+	// EncodingCbr encodes at a constant bitrate.
+	EncodingCbr EncodingMode = iota
+
+	// EncodingAbr encodes at a variable bitrate that averages out to the profile's bitrate.
+	EncodingAbr
+
+	// EncodingVbr encodes at a variable bitrate.
+	// Depending on the format, the bitrate is either controlled by the profile's quality or targets the profile's bitrate.
+	EncodingVbr
+
+	// EncodingCompression encodes losslessly with the profile's quality as the compression level.
+	EncodingCompression
+)
+
+// EncodingModeOptions describes how a format is encoded with an encoding mode.
+type EncodingModeOptions struct {
+	// The FFmpeg arguments that select the mode, such as "-abr", "1".
+	Args []string
+
+	// The FFmpeg option that the profile's quality is passed to, such as "-q:a".
+	// If empty, the mode is controlled by the profile's bitrate instead.
+	QualityOption string
+
+	// The lowest valid quality.
+	MinQuality float64
+
+	// The highest valid quality.
+	MaxQuality float64
+
+	// The suggested quality.
+	// Profiles created before encoding modes existed are assumed to use it.
+	SuggestedQuality float64
+
+	// Whether the quality must be a whole number.
+	IsIntegerQuality bool
+}
+
+// UsesQuality returns whether the mode is controlled by the profile's quality rather than its bitrate.
+func (o EncodingModeOptions) UsesQuality() bool {
+	return o.QualityOption != ""
+}
+
+// IsQualityValid returns whether the specified quality is in the mode's range.
+func (o EncodingModeOptions) IsQualityValid(quality float64) bool {
+	if quality < o.MinQuality || quality > o.MaxQuality {
+		return false
+	}
+
+	return !o.IsIntegerQuality || quality == math.Trunc(quality)
+}
+
+// GetEncodingModes returns the encoding modes supported by the format, in ascending order.
+func (f OutputFormat) GetEncodingModes() []EncodingMode {
+	res := make([]EncodingMode, 0, len(f.EncodingModes))
+	for mode := range f.EncodingModes {
+		res = append(res, mode)
+	}
+	slices.Sort(res)
+
+	return res
+}
+
+// GetEncodingModeOptions returns the options of the profile's encoding mode.
+// If the profile's format does not support the mode, the zero value is returned.
+func (p *OutputProfile) GetEncodingModeOptions() EncodingModeOptions {
+	return p.OutputFormat.EncodingModes[p.EncodingMode]
+}
+
+// IsEncodingValid returns whether the profile's format supports its encoding mode, and whether its quality is in the
+// mode's range.
+// Formats without any encoding modes only accept their default mode.
+func (p *OutputProfile) IsEncodingValid() bool {
+	opts, ok := p.OutputFormat.EncodingModes[p.EncodingMode]
+	if !ok {
+		return len(p.OutputFormat.EncodingModes) == 0 && p.EncodingMode == p.OutputFormat.DefaultEncodingMode
+	}
+
+	return !opts.UsesQuality() || opts.IsQualityValid(p.Quality)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOutputProfileIsEncodingValid(t *testing.T) {
+	mp3 := SupportedOutputFormats[0]
+	flac := SupportedOutputFormats[1]
+	wav := SupportedOutputFormats[2]
+
+	tests := []struct {
+		name string
+		prof OutputProfile
+		want bool
+	}{
+		{"bitrate mode", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingAbr, Bitrate: 192000}, true},
+		{"bitrate mode ignores the quality", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingCbr, Quality: 99}, true},
+		{"quality in range", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingVbr, Quality: 9}, true},
+		{"fractional quality", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingVbr, Quality: 2.5}, true},
+		{"quality above the range", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingVbr, Quality: 10}, false},
+		{"quality below the range", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingVbr, Quality: -1}, false},
+		{"unsupported mode", OutputProfile{OutputFormat: mp3, EncodingMode: EncodingCompression}, false},
+		{"unknown mode", OutputProfile{OutputFormat: mp3, EncodingMode: 99}, false},
+		{"compression level", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 12}, true},
+		{"fractional compression level", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 5.5}, false},
+		{"format without modes in its default mode", OutputProfile{OutputFormat: wav}, true},
+		{"format without modes in another mode", OutputProfile{OutputFormat: wav, EncodingMode: EncodingVbr}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.prof.IsEncodingValid(); got != test.want {
+			t.Errorf("%s: IsEncodingValid() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOutputFormatGetEncodingModes(t *testing.T) {
+	if got, want := SupportedOutputFormats[0].GetEncodingModes(), []EncodingMode{EncodingCbr, EncodingAbr, EncodingVbr}; !slices.Equal(got, want) {
+		t.Errorf("GetEncodingModes() of MP3 = %v, want %v", got, want)
+	}
+	if got := SupportedOutputFormats[2].GetEncodingModes(); len(got) != 0 {
+		t.Errorf("GetEncodingModes() of WAV = %v, want none", got)
+	}
+}
+
+func TestBuiltInEncodingModes(t *testing.T) {
+	// Every built-in format must be able to encode with its default mode and suggested quality, which profiles created
+	// before encoding modes existed are upgraded to
+	for id, format := range SupportedOutputFormats {
+		prof := OutputProfile{
+			OutputFormat: format,
+			EncodingMode: format.DefaultEncodingMode,
+			Quality:      format.EncodingModes[format.DefaultEncodingMode].SuggestedQuality,
+		}
+		if !prof.IsEncodingValid() {
+			t.Errorf("format %d (%s) is not valid in its default encoding mode", id, format.Name)
+		}
+
+		for mode, opts := range format.EncodingModes {
+			if opts.UsesQuality() && !opts.IsQualityValid(opts.SuggestedQuality) {
+				t.Errorf("format %d (%s) suggests an invalid quality for mode %d", id, format.Name, mode)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidExtraArgs is returned when a profile's extra FFmpeg arguments are malformed or contain an option that is
+// not allowed.
+var ErrInvalidExtraArgs = errors.New("{{config.error.invalid-extra-args}}")
+
+// ErrInvalidAudioFilter is returned when a profile's audio filter chain is malformed or contains a filter that is not
+// allowed.
+var ErrInvalidAudioFilter = errors.New("{{config.error.invalid-audio-filter}}")
+
+// flagOptions are the FFmpeg options that are allowed in extra arguments without a value.
+var flagOptions = []string{"vn", "sn", "dn", "shortest"}
+
+// deniedOptions are the FFmpeg options that are not allowed in extra arguments, without stream specifiers.
+// They either read or write files other than the source and output, such as preset files, pass logs and statistics,
+// change the output's encoders or container, or replace the filters that syncing relies on.
+var deniedOptions = []string{
+	"i", "y", "n", "f",
+	"c", "codec", "acodec", "vcodec", "an",
+	"af", "filter", "filter_complex", "lavfi", "filter_script", "filter_complex_script",
+	"pre", "fpre", "apre", "vpre", "spre",
+	"progress", "report", "vstats", "vstats_file", "pass", "passlogfile", "sdp_file", "attach", "dump_attachment",
+	"stats_enc_pre", "stats_enc_post", "stats_mux_pre",
+}
+
+// deniedFilters are the FFmpeg filters that are not allowed in audio filter chains, since they read other files.
+var deniedFilters = []string{"movie", "amovie"}
+
+// ParseArgs splits a string into arguments like a shell does.
+// Arguments are separated by whitespace, and can be quoted with single or double quotes.
+// Inside double quotes and outside of quotes, a backslash escapes the next character.
+// If a quote is not closed or the string ends with a backslash, ErrInvalidExtraArgs is returned.
+func ParseArgs(str string) ([]string, error) {
+	var res []string
+	var arg strings.Builder
+	var quote rune
+	hasArg := false
+	isEscaped := false
+
+	for _, char := range str {
+		switch {
+		case isEscaped:
+			arg.WriteRune(char)
+			isEscaped = false
+		case char == '\\' && quote != '\'':
+			isEscaped = true
+			hasArg = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(char)
+			}
+		case char == '"' || char == '\'':
+			quote = char
+			hasArg = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if hasArg {
+				res = append(res, arg.String())
+				arg.Reset()
+				hasArg = false
+			}
+		default:
+			arg.WriteRune(char)
+			hasArg = true
+		}
+	}
+
+	if quote != 0 || isEscaped {
+		return nil, ErrInvalidExtraArgs
+	}
+	if hasArg {
+		res = append(res, arg.String())
+	}
+
+	return res, nil
+}
+
+// FormatArgs joins arguments into a string accepted by ParseArgs.
+// Arguments that contain whitespace, quotes or backslashes are quoted.
+func FormatArgs(args []string) string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\") {
+			res[i] = arg
+			continue
+		}
+
+		res[i] = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+	}
+
+	return strings.Join(res, " ")
+}
+
+// isOption returns whether an argument is an FFmpeg option rather than a value.
+// Negative numbers are values.
+func isOption(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && (arg[1] < '0' || arg[1] > '9') && arg[1] != '.'
+}
+
+// ValidateExtraArgs checks that extra FFmpeg arguments can safely be passed to FFmpeg as output options.
+// Every option must be followed by a single value, except for a few options that don't take one, so that no argument
+// can be taken as another output path.
+// Options that read or write other files, change the output's encoder or container, or replace the audio filters are
+// not allowed.
+// If the arguments are not valid, ErrInvalidExtraArgs is returned.
+func ValidateExtraArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		if !isOption(args[i]) {
+			return ErrInvalidExtraArgs
+		}
+
+		// Options such as "-/filter" read their value from a file.
+		// Stream specifiers such as the ":a" in "-c:a" don't change what an option does.
+		name := args[i][1:]
+		baseName, _, _ := strings.Cut(name, ":")
+		if strings.HasPrefix(name, "/") || slices.Contains(deniedOptions, baseName) {
+			return ErrInvalidExtraArgs
+		}
+
+		if slices.Contains(flagOptions, name) {
+			continue
+		}
+
+		if i+1 >= len(args) || isOption(args[i+1]) {
+			return ErrInvalidExtraArgs
+		}
+		i++
+	}
+
+	return nil
+}
+
+// splitEscaped splits a string by a separator, ignoring separators that are escaped with a backslash or inside single
+// quotes, as FFmpeg filter graphs do.
+func splitEscaped(str string, sep rune) []string {
+	var res []string
+	start := 0
+	isQuoted := false
+	isEscaped := false
+
+	for i, char := range str {
+		switch {
+		case isEscaped:
+			isEscaped = false
+		case char == '\\':
+			isEscaped = true
+		case char == '\'':
+			isQuoted = !isQuoted
+		case char == sep && !isQuoted:
+			res = append(res, str[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(res, str[start:])
+}
+
+// ValidateAudioFilter checks that an FFmpeg audio filter chain, such as "loudnorm,volume=-3dB", can safely be applied
+// to outputs.
+// The chain must be a single, linear chain without link labels.
+// Filters that read other files, and filter options that refer to files, are not allowed.
+// If the chain is not valid, ErrInvalidAudioFilter is returned.
+func ValidateAudioFilter(filter string) error {
+	if strings.TrimSpace(filter) == "" {
+		return ErrInvalidAudioFilter
+	}
+
+	for _, part := range splitEscaped(filter, ',') {
+		if strings.ContainsAny(part, "[];") {
+			return ErrInvalidAudioFilter
+		}
+
+		name, opts, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" || slices.Contains(deniedFilters, name) {
+			return ErrInvalidAudioFilter
+		}
+
+		for _, opt := range splitEscaped(opts, ':') {
+			optName, _, _ := strings.Cut(opt, "=")
+			if optName == "file" || optName == "filename" {
+				return ErrInvalidAudioFilter
+			}
+		}
+	}
+
+	return nil
+}
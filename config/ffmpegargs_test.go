@@ -0,0 +1,168 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"  -q:a  2 \t\n", []string{"-q:a", "2"}, false},
+		{`-metadata "comment=hello world"`, []string{"-metadata", "comment=hello world"}, false},
+		{`-metadata 'comment=a "quoted" word'`, []string{"-metadata", `comment=a "quoted" word`}, false},
+		{`-metadata comment=it\'s`, []string{"-metadata", "comment=it's"}, false},
+		{`-metadata "comment=back\\slash \"quote\""`, []string{"-metadata", `comment=back\slash "quote"`}, false},
+
+		// Backslashes are literal inside single quotes
+		{`'a\b'`, []string{`a\b`}, false},
+
+		// Empty quotes are an empty argument
+		{`-metadata ''`, []string{"-metadata", ""}, false},
+		{`a""b`, []string{"ab"}, false},
+
+		{`"unclosed`, nil, true},
+		{`'unclosed`, nil, true},
+		{`trailing\`, nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseArgs(test.str)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseArgs(%q) error = %v, want error %v", test.str, err, test.wantErr)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("ParseArgs(%q) = %q, want %q", test.str, got, test.want)
+		}
+	}
+}
+
+func TestFormatArgsRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"-q:a", "2"},
+		{"-metadata", "comment=hello world"},
+		{"-metadata", "comment=it's"},
+		{"-metadata", `comment="quoted"`},
+		{"-metadata", `back\slash`},
+		{"-metadata", ""},
+		{"-metadata", "tab\tand\nnewline"},
+		{"-metadata", `mixed 'single' "double" \ all`},
+	}
+
+	for _, args := range tests {
+		str := FormatArgs(args)
+		got, err := ParseArgs(str)
+		if err != nil {
+			t.Errorf("ParseArgs(FormatArgs(%q)) = %q: %v", args, str, err)
+			continue
+		}
+		if !slices.Equal(got, args) {
+			t.Errorf("ParseArgs(FormatArgs(%q)) = %q via %q", args, got, str)
+		}
+	}
+
+	if got := FormatArgs([]string{"-q:a", "2"}); got != "-q:a 2" {
+		t.Errorf("FormatArgs quoted plain arguments: %q", got)
+	}
+}
+
+func TestValidateExtraArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		valid bool
+	}{
+		{nil, true},
+		{[]string{"-q:a", "2"}, true},
+		{[]string{"-compression_level", "8", "-vn"}, true},
+		{[]string{"-metadata", "comment=x", "-shortest"}, true},
+
+		// Negative numbers are values rather than options
+		{[]string{"-cutoff", "-1"}, true},
+		{[]string{"-global_quality", "-.5"}, true},
+
+		// Every option needs a value, so that no argument can be taken as an output path
+		{[]string{"-q:a"}, false},
+		{[]string{"-q:a", "-vn"}, false},
+		{[]string{"out.mp3"}, false},
+		{[]string{"-q:a", "2", "out.mp3"}, false},
+		{[]string{"-"}, false},
+
+		// Stream specifiers don't bypass denied options
+		{[]string{"-c:a", "pcm_s16le"}, false},
+		{[]string{"-codec:a:0", "flac"}, false},
+		{[]string{"-filter:a", "volume=2"}, false},
+		{[]string{"-af", "volume=2"}, false},
+
+		// Options that read their value from a file
+		{[]string{"-/filter:a", "filter.txt"}, false},
+		{[]string{"-/af", "filter.txt"}, false},
+
+		// Options that read or write other files
+		{[]string{"-i", "other.flac"}, false},
+		{[]string{"-y", "x"}, false},
+		{[]string{"-f", "wav"}, false},
+		{[]string{"-passlogfile", "log"}, false},
+		{[]string{"-pass", "1"}, false},
+		{[]string{"-fpre", "preset.ffpreset"}, false},
+		{[]string{"-apre", "preset"}, false},
+		{[]string{"-pre:a", "preset"}, false},
+		{[]string{"-stats_enc_pre:a", "stats.txt"}, false},
+		{[]string{"-stats_enc_post", "stats.txt"}, false},
+		{[]string{"-stats_mux_pre", "stats.txt"}, false},
+		{[]string{"-attach", "cover.jpg"}, false},
+		{[]string{"-progress", "progress.txt"}, false},
+	}
+
+	for _, test := range tests {
+		err := ValidateExtraArgs(test.args)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("ValidateExtraArgs(%q) = %v, want valid %v", test.args, err, test.valid)
+		}
+		if err != nil && err != ErrInvalidExtraArgs {
+			t.Errorf("ValidateExtraArgs(%q) returned %v instead of ErrInvalidExtraArgs", test.args, err)
+		}
+	}
+}
+
+func TestValidateAudioFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		valid  bool
+	}{
+		{"loudnorm", true},
+		{"loudnorm,volume=-3dB", true},
+		{"highpass=f=40:p=2, lowpass=f=18000", true},
+
+		// Separators that are escaped or quoted are part of the option value
+		{`volume='0.5,1'`, true},
+		{`aeval=val(0)\,val(1)`, true},
+
+		{"", false},
+		{"   ", false},
+		{"loudnorm,", false},
+
+		// Link labels and multiple chains would let the filter graph read other inputs
+		{"[0:a]loudnorm", false},
+		{"loudnorm[out]", false},
+		{"loudnorm;volume=2", false},
+
+		// Filters and options that read other files
+		{"movie=other.wav", false},
+		{"amovie=other.wav,volume=2", false},
+		{"volume=2, amovie=other.wav", false},
+		{"sofalizer=filename=hrtf.sofa", false},
+		{"ladspa=f=plugin:file=x", false},
+	}
+
+	for _, test := range tests {
+		err := ValidateAudioFilter(test.filter)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("ValidateAudioFilter(%q) = %v, want valid %v", test.filter, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ErrInvalidFilterRule is returned when a filter rule can't be parsed or has an invalid glob pattern.
+var ErrInvalidFilterRule = errors.New("{{config.error.invalid-filter-rule}}")
+
+// FilterRule is a rule that includes or excludes source files whose paths match a glob pattern.
+type FilterRule struct {
+	// Whether matching files are excluded instead of included.
+	Exclude bool
+
+	// The glob pattern to match against paths relative to the source directory, using "/" as the separator.
+	// Supports the syntax of path.Match, plus "**", which matches any number of directories.
+	// Patterns without a "/" are matched against the file name at any depth, so "*.log" matches every log file.
+	// A pattern that matches a directory matches everything inside it.
+	Pattern string
+}
+
+// ParseFilterRule parses a filter rule in the form "+ pattern" to include or "- pattern" to exclude.
+// If the rule is malformed or its pattern is invalid, ErrInvalidFilterRule is returned.
+func ParseFilterRule(str string) (FilterRule, error) {
+	str = strings.TrimSpace(str)
+	if len(str) < 2 || (str[0] != '+' && str[0] != '-') {
+		return FilterRule{}, ErrInvalidFilterRule
+	}
+
+	rule := FilterRule{
+		Exclude: str[0] == '-',
+		Pattern: strings.TrimSpace(str[1:]),
+	}
+	if !rule.IsValid() {
+		return FilterRule{}, ErrInvalidFilterRule
+	}
+
+	return rule, nil
+}
+
+// String returns the rule in the form accepted by ParseFilterRule.
+func (r FilterRule) String() string {
+	if r.Exclude {
+		return "- " + r.Pattern
+	}
+
+	return "+ " + r.Pattern
+}
+
+// IsValid returns whether the rule's pattern is a valid glob pattern.
+func (r FilterRule) IsValid() bool {
+	if r.Pattern == "" {
+		return false
+	}
+
+	for _, part := range strings.Split(r.Pattern, "/") {
+		if _, err := path.Match(part, ""); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches returns whether the rule's pattern matches the specified path or any of its parent directories.
+// The path must be relative to the source directory and use "/" as the separator.
+func (r FilterRule) Matches(relPath string) bool {
+	pattern := strings.Trim(r.Pattern, "/")
+	isNamePattern := !strings.Contains(pattern, "/")
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(relPath, "/")
+
+	// Matching a directory matches everything inside it, so try every parent directory as well as the path itself
+	for i := len(pathParts); i > 0; i-- {
+		if isNamePattern {
+			if matchGlobParts(patternParts, pathParts[i-1:i]) {
+				return true
+			}
+		} else if matchGlobParts(patternParts, pathParts[:i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlobParts returns whether the pattern parts match the path parts.
+// A "**" part matches any number of path parts, including none.
+func matchGlobParts(patternParts []string, pathParts []string) bool {
+	for len(patternParts) > 0 {
+		if patternParts[0] == "**" {
+			// Try consuming every possible number of path parts
+			for i := 0; i <= len(pathParts); i++ {
+				if matchGlobParts(patternParts[1:], pathParts[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(pathParts) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(patternParts[0], pathParts[0]); !ok {
+			return false
+		}
+
+		patternParts = patternParts[1:]
+		pathParts = pathParts[1:]
+	}
+
+	return len(pathParts) == 0
+}
+
+// IsIncluded returns whether a source file is included by the sync's filter rules.
+// The path must be relative to the source directory and use "/" as the separator.
+// The first rule that matches the path decides, so narrower rules should come before broader ones.
+// Files that don't match any rule are included.
+func (s *SyncConfig) IsIncluded(relPath string) bool {
+	for _, rule := range s.FilterRules {
+		if rule.Matches(relPath) {
+			return !rule.Exclude
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,119 @@
+package config
+
+import "testing"
+
+func TestFilterRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Patterns without a "/" match the file name, or any directory name, at any depth
+		{"*.log", "a.log", true},
+		{"*.log", "x/y/a.log", true},
+		{"*.log", "a.log.txt", false},
+		{"Scans", "Album/Scans/01.jpg", true},
+		{"Scans", "Album/NotScans/01.jpg", false},
+
+		// Patterns with a "/" match from the start of the path
+		{"Album/*.flac", "Album/01.flac", true},
+		{"Album/*.flac", "Other/Album/01.flac", false},
+		{"Album/*.flac", "Album/CD1/01.flac", false},
+
+		// Matching a directory matches everything inside it
+		{"Album/CD1", "Album/CD1/01.flac", true},
+		{"Album/CD1", "Album/CD1/Scans/01.jpg", true},
+		{"Album/CD1", "Album/CD10/01.flac", false},
+		{"Album/CD1", "Album", false},
+
+		// "**" matches any number of directories, including none
+		{"**/*.flac", "01.flac", true},
+		{"**/*.flac", "a/b/c/01.flac", true},
+		{"Album/**/01.flac", "Album/01.flac", true},
+		{"Album/**/01.flac", "Album/CD1/Disc/01.flac", true},
+		{"Album/**/01.flac", "Other/01.flac", false},
+		{"Album/**", "Album/CD1/01.flac", true},
+		{"**", "any/path.mp3", true},
+		{"a/**/b/**/c", "a/b/c", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b/**/c", "a/x/c", false},
+
+		// Leading and trailing slashes are ignored, so they don't turn name patterns into path patterns
+		{"/Album/*.flac", "Album/01.flac", true},
+		{"Album/", "Album/01.flac", true},
+		{"/Album/", "Album/01.flac", true},
+		{"/*.log", "x/a.log", true},
+		{"Scans/", "Album/Scans/01.jpg", true},
+	}
+
+	for _, test := range tests {
+		rule := FilterRule{Pattern: test.pattern}
+		if got := rule.Matches(test.path); got != test.want {
+			t.Errorf("%q.Matches(%q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestSyncConfigIsIncluded(t *testing.T) {
+	sync := &SyncConfig{
+		FilterRules: []FilterRule{
+			{Exclude: false, Pattern: "Album/Scans/cover.jpg"},
+			{Exclude: true, Pattern: "Scans"},
+			{Exclude: true, Pattern: "*.log"},
+			{Exclude: false, Pattern: "Keep/*.log"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		// The first matching rule decides, so the narrower include wins over the broader exclude after it
+		{"Album/Scans/cover.jpg", true},
+		{"Album/Scans/back.jpg", false},
+
+		// The include after the broader exclude never gets a say
+		{"Keep/rip.log", false},
+
+		// Files that match no rule are included
+		{"Album/01.flac", true},
+	}
+
+	for _, test := range tests {
+		if got := sync.IsIncluded(test.path); got != test.want {
+			t.Errorf("IsIncluded(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+
+	if !(&SyncConfig{}).IsIncluded("any/file.flac") {
+		t.Error("IsIncluded without rules = false, want true")
+	}
+}
+
+func TestParseFilterRule(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    FilterRule
+		wantErr bool
+	}{
+		{"+ *.flac", FilterRule{Exclude: false, Pattern: "*.flac"}, false},
+		{"-  Scans/ ", FilterRule{Exclude: true, Pattern: "Scans/"}, false},
+		{"*.flac", FilterRule{}, true},
+		{"+", FilterRule{}, true},
+		{"- [", FilterRule{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseFilterRule(test.str)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseFilterRule(%q) error = %v, want error %v", test.str, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseFilterRule(%q) = %+v, want %+v", test.str, got, test.want)
+		}
+		if reparsed, _ := ParseFilterRule(got.String()); err == nil && reparsed != got {
+			t.Errorf("ParseFilterRule(%q) does not round-trip through String: %+v", test.str, reparsed)
+		}
+	}
+}
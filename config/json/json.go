@@ -6,11 +6,17 @@ import (
 	"errors"
 	"github.com/termermc/your-loss-sync/config"
 	"io"
+	"maps"
+	"slices"
+	"time"
 )
 
 const (
 	// Version1 is config JSON version 1.
 	Version1 = 1
+
+	// Version2 is config JSON version 2.
+	Version2 = 2
 )
 
 // ConfigBase is the struct that all JSON configs must implement.
@@ -29,18 +35,54 @@ var ErrUnknownProfile = errors.New("{{config.error.unknown-profile}}")
 // ErrUnknownFormat is returned when a config contains a reference to an unknown format.
 var ErrUnknownFormat = errors.New("{{config.error.unknown-format}}")
 
+// ErrInvalidEncoding is returned when a config contains a profile whose encoding mode is not supported by its format, or
+// whose quality is out of range.
+var ErrInvalidEncoding = errors.New("{{config.error.invalid-encoding}}")
+
+// ErrInvalidId3Version is returned when a config contains a profile with an unsupported ID3v2 version.
+var ErrInvalidId3Version = errors.New("{{config.error.invalid-id3-version}}")
+
+// ErrInvalidArtwork is returned when a config contains a profile with an unknown artwork policy or an invalid JPEG
+// quality.
+var ErrInvalidArtwork = errors.New("{{config.error.invalid-artwork}}")
+
+// ErrInvalidChannelPolicy is returned when a config contains a profile with an unknown channel policy.
+var ErrInvalidChannelPolicy = errors.New("{{config.error.invalid-channel-policy}}")
+
+// ErrInvalidFilePolicy is returned when a config contains a sync with an unknown file policy.
+var ErrInvalidFilePolicy = errors.New("{{config.error.invalid-file-policy}}")
+
+// ErrInvalidRetryPolicy is returned when a config contains a retry policy with an unknown error class or a negative
+// backoff.
+var ErrInvalidRetryPolicy = errors.New("{{config.error.invalid-retry-policy}}")
+
 // DeserializeFromJson deserializes a JSON configuration from the given reader.
 // If the config version is not supported, ErrUnsupportedVersion is returned.
 // If the config contains an unknown profile, ErrUnknownProfile is returned.
 // If the config contains an unknown format, ErrUnknownFormat is returned.
+// If the config contains a conversion rule with an unknown action, config.ErrInvalidConversionRule is returned.
+// If the config contains an invalid tag rule, config.ErrInvalidTagRule is returned.
+// If the config contains an invalid folder image file name, config.ErrInvalidArtworkFilename is returned.
+// If the config contains an invalid custom format, config.ErrInvalidCustomFormat is returned.
+// If the config contains a profile with an invalid encoding mode, ErrInvalidEncoding is returned.
+// If the config contains a profile with an unsupported ID3v2 version, ErrInvalidId3Version is returned.
+// If the config contains a profile with invalid artwork options, ErrInvalidArtwork is returned.
+// If the config contains a profile with an unknown channel policy, ErrInvalidChannelPolicy is returned.
+// If the config contains a sync with an unknown file policy, ErrInvalidFilePolicy is returned.
+// If the config contains an invalid retry policy, ErrInvalidRetryPolicy is returned.
+// If the config contains a profile with invalid extra FFmpeg arguments or audio filters, config.ErrInvalidExtraArgs or
+// config.ErrInvalidAudioFilter is returned.
+// Version 1 configs are upgraded to version 2.
 // The returned config will be valid if no error is returned.
 func DeserializeFromJson(reader io.Reader) (*config.Config, error) {
 	// Buffer entire reader to memory so that we can read it again if needed.
-	buffer := make([]byte, 1024)
-	_, err := reader.Read(buffer)
+	buffer, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
+	if len(buffer) == 0 {
+		return nil, io.EOF
+	}
 
 	bufReader := bytes.NewReader(buffer)
 
@@ -61,85 +103,380 @@ func DeserializeFromJson(reader io.Reader) (*config.Config, error) {
 			return nil, err
 		}
 
-		resProfiles := make([]*config.OutputProfile, len(v1.Profiles))
-		for i, v1Profile := range v1.Profiles {
-			format, ok := config.SupportedOutputFormats[v1Profile.OutputFormatId]
-			if !ok {
-				return nil, ErrUnknownFormat
+		return fromV2(upgradeV1(v1))
+
+	case Version2:
+		var v2 V2
+		err = json.NewDecoder(bufReader).Decode(&v2)
+		if err != nil {
+			return nil, err
+		}
+
+		return fromV2(v2)
+
+	default:
+		return nil, ErrUnsupportedVersion
+
+	}
+}
+
+// upgradeV1 converts a version 1 config to version 2.
+// Profiles are given the encoding mode that their format was encoded with before encoding modes existed.
+func upgradeV1(v1 V1) V2 {
+	profiles := make([]V2OutputProfile, len(v1.Profiles))
+	for i, v1Profile := range v1.Profiles {
+		// Unknown formats are reported when the upgraded config is read
+		format := config.SupportedOutputFormats[v1Profile.OutputFormatId]
+
+		profiles[i] = V2OutputProfile{
+			Name:           v1Profile.Name,
+			OutputFormatId: v1Profile.OutputFormatId,
+			EncodingMode:   int(format.DefaultEncodingMode),
+			Bitrate:        v1Profile.Bitrate,
+			Quality:        format.EncodingModes[format.DefaultEncodingMode].SuggestedQuality,
+		}
+	}
+
+	syncs := make([]V2Sync, len(v1.Syncs))
+	for i, v1Sync := range v1.Syncs {
+		syncs[i] = V2Sync{
+			Name:               v1Sync.Name,
+			SourceDir:          v1Sync.SourceDir,
+			DestDir:            v1Sync.DestDir,
+			ProfileName:        v1Sync.ProfileName,
+			EscapeFilenames:    v1Sync.EscapeFilenames,
+			ReencodeSameFormat: v1Sync.ReencodeSameFormat,
+		}
+	}
+
+	return V2{
+		Version:  Version2,
+		LangCode: v1.LangCode,
+		Syncs:    syncs,
+		Profiles: profiles,
+	}
+}
+
+// fromV2 converts a version 2 config to a config.
+// See DeserializeFromJson for the errors it returns.
+func fromV2(v2 V2) (*config.Config, error) {
+	res := &config.Config{
+		CustomFormats: make(map[int]config.OutputFormat, len(v2.CustomFormats)),
+	}
+	for _, v2Format := range v2.CustomFormats {
+		if _, has := res.CustomFormats[v2Format.Id]; has {
+			return nil, config.ErrInvalidCustomFormat
+		}
+
+		format := config.OutputFormat{
+			IsLossless:       v2Format.IsLossless,
+			Name:             v2Format.Name,
+			Extension:        v2Format.Extension,
+			FfmpegEncoder:    v2Format.FfmpegEncoder,
+			SupportsMetadata: v2Format.SupportsMetadata,
+			TagStyle:         config.TagStyle(v2Format.TagStyle),
+			SupportsArtwork:  v2Format.SupportsArtwork,
+			SuggestedBitrate: v2Format.SuggestedBitrate,
+			CodecNames:       v2Format.CodecNames,
+			Containers:       v2Format.Containers,
+			MaxChannels:      v2Format.MaxChannels,
+		}
+		if err := res.ValidateCustomFormat(v2Format.Id, format); err != nil {
+			return nil, err
+		}
+
+		res.CustomFormats[v2Format.Id] = format
+	}
+
+	formats := res.GetOutputFormats()
+
+	resProfiles := make([]*config.OutputProfile, len(v2.Profiles))
+	for i, v2Profile := range v2.Profiles {
+		format, ok := formats[v2Profile.OutputFormatId]
+		if !ok {
+			return nil, ErrUnknownFormat
+		}
+
+		resProfiles[i] = &config.OutputProfile{
+			Name:             v2Profile.Name,
+			OutputFormat:     format,
+			EncodingMode:     config.EncodingMode(v2Profile.EncodingMode),
+			Bitrate:          v2Profile.Bitrate,
+			Quality:          v2Profile.Quality,
+			MaxSampleRate:    v2Profile.MaxSampleRate,
+			MaxBitDepth:      v2Profile.MaxBitDepth,
+			DitherMethod:     v2Profile.DitherMethod,
+			ChannelPolicy:    config.ChannelPolicy(v2Profile.ChannelPolicy),
+			Id3Version:       v2Profile.Id3Version,
+			ArtworkPolicy:    config.ArtworkPolicy(v2Profile.ArtworkPolicy),
+			MaxArtworkSize:   v2Profile.MaxArtworkSize,
+			ArtworkQuality:   v2Profile.ArtworkQuality,
+			ExtraArgs:        v2Profile.ExtraArgs,
+			AudioFilter:      v2Profile.AudioFilter,
+			CompatibleCodecs: v2Profile.CompatibleCodecs,
+		}
+		if !resProfiles[i].IsEncodingValid() {
+			return nil, ErrInvalidEncoding
+		}
+		if !resProfiles[i].IsId3VersionValid() {
+			return nil, ErrInvalidId3Version
+		}
+		if !resProfiles[i].IsArtworkValid() {
+			return nil, ErrInvalidArtwork
+		}
+		if !resProfiles[i].ChannelPolicy.IsValid() {
+			return nil, ErrInvalidChannelPolicy
+		}
+		if err := config.ValidateExtraArgs(v2Profile.ExtraArgs); err != nil {
+			return nil, err
+		}
+		if v2Profile.AudioFilter != "" {
+			if err := config.ValidateAudioFilter(v2Profile.AudioFilter); err != nil {
+				return nil, err
 			}
+		}
+	}
 
-			resProfiles[i] = &config.OutputProfile{
-				Name:         v1Profile.Name,
-				OutputFormat: format,
-				Bitrate:      v1Profile.Bitrate,
+	resSyncs := make([]*config.SyncConfig, len(v2.Syncs))
+	for i, v2Sync := range v2.Syncs {
+		var profile *config.OutputProfile
+		for _, p := range resProfiles {
+			if p.Name == v2Sync.ProfileName {
+				profile = p
+				break
 			}
 		}
+		if profile == nil {
+			return nil, ErrUnknownProfile
+		}
+
+		conversionRules := make([]config.ConversionRule, len(v2Sync.ConversionRules))
+		for j, v2Rule := range v2Sync.ConversionRules {
+			conversionRules[j] = config.ConversionRule{
+				Codecs:        v2Rule.Codecs,
+				Extensions:    v2Rule.Extensions,
+				MinSampleRate: v2Rule.MinSampleRate,
+				MaxSampleRate: v2Rule.MaxSampleRate,
+				MinBitDepth:   v2Rule.MinBitDepth,
+				MaxBitDepth:   v2Rule.MaxBitDepth,
+				Action:        config.ConversionAction(v2Rule.Action),
+			}
 
-		resSyncs := make([]*config.SyncConfig, len(v1.Syncs))
-		for i, v1Sync := range v1.Syncs {
-			var profile *config.OutputProfile
-			for _, p := range resProfiles {
-				if p.Name == v1Sync.ProfileName {
-					profile = p
-					break
+			if conversionRules[j].Action == config.ConversionTranscode {
+				for _, p := range resProfiles {
+					if p.Name == v2Rule.ProfileName {
+						conversionRules[j].Profile = p
+						break
+					}
+				}
+				if conversionRules[j].Profile == nil {
+					return nil, ErrUnknownProfile
 				}
 			}
-			if profile == nil {
-				return nil, ErrUnknownProfile
+			if err := conversionRules[j].Validate(); err != nil {
+				return nil, err
 			}
+		}
 
-			resSyncs[i] = &config.SyncConfig{
-				Name:               v1Sync.Name,
-				SourceDir:          v1Sync.SourceDir,
-				DestDir:            v1Sync.DestDir,
-				Profile:            profile,
-				EscapeFilenames:    v1Sync.EscapeFilenames,
-				ReencodeSameFormat: v1Sync.ReencodeSameFormat,
+		filterRules := make([]config.FilterRule, len(v2Sync.FilterRules))
+		for j, v2Rule := range v2Sync.FilterRules {
+			filterRules[j] = config.FilterRule{
+				Exclude: v2Rule.Exclude,
+				Pattern: v2Rule.Pattern,
+			}
+			if !filterRules[j].IsValid() {
+				return nil, config.ErrInvalidFilterRule
 			}
 		}
 
-		return &config.Config{
-			LangCode: v1.LangCode,
-			Profiles: resProfiles,
-			Syncs:    resSyncs,
-		}, nil
+		tagRules := make([]config.TagRule, len(v2Sync.TagRules))
+		for j, v2Rule := range v2Sync.TagRules {
+			tagRules[j] = config.TagRule{
+				Action:  config.TagAction(v2Rule.Action),
+				Tag:     v2Rule.Tag,
+				Value:   v2Rule.Value,
+				Source:  v2Rule.Source,
+				Pattern: v2Rule.Pattern,
+			}
+			if err := tagRules[j].Compile(); err != nil {
+				return nil, err
+			}
+		}
 
-	default:
-		return nil, ErrUnsupportedVersion
+		if !config.FilePolicy(v2Sync.FilePolicy).IsValid() {
+			return nil, ErrInvalidFilePolicy
+		}
 
+		for _, name := range v2Sync.ArtworkFilenames {
+			if !config.IsArtworkFilenameValid(name) {
+				return nil, config.ErrInvalidArtworkFilename
+			}
+		}
+
+		resSyncs[i] = &config.SyncConfig{
+			Name:               v2Sync.Name,
+			SourceDir:          v2Sync.SourceDir,
+			DestDir:            v2Sync.DestDir,
+			Profile:            profile,
+			ConversionRules:    conversionRules,
+			TagRules:           tagRules,
+			EscapeFilenames:    v2Sync.EscapeFilenames,
+			ReencodeSameFormat: v2Sync.ReencodeSameFormat,
+			SmartTranscode:     v2Sync.SmartTranscode,
+			Mirror:             v2Sync.Mirror,
+			Concurrency:        v2Sync.Concurrency,
+			LowPriority:        v2Sync.LowPriority,
+			FilterRules:        filterRules,
+			FilePolicy:         config.FilePolicy(v2Sync.FilePolicy),
+			SidecarExtensions:  v2Sync.SidecarExtensions,
+			EmbedFolderArtwork: v2Sync.EmbedFolderArtwork,
+			ArtworkFilenames:   v2Sync.ArtworkFilenames,
+		}
 	}
+
+	retryPolicy := config.DefaultRetryPolicy()
+	if v2.RetryPolicy != nil {
+		if v2.RetryPolicy.BackoffMs < 0 {
+			return nil, ErrInvalidRetryPolicy
+		}
+
+		retryPolicy = config.RetryPolicy{
+			MaxRetries:      v2.RetryPolicy.MaxRetries,
+			Backoff:         time.Duration(v2.RetryPolicy.BackoffMs) * time.Millisecond,
+			RetryableErrors: make([]config.RetryErrorClass, len(v2.RetryPolicy.RetryableErrors)),
+		}
+		for i, class := range v2.RetryPolicy.RetryableErrors {
+			retryPolicy.RetryableErrors[i] = config.RetryErrorClass(class)
+			if !retryPolicy.RetryableErrors[i].IsValid() {
+				return nil, ErrInvalidRetryPolicy
+			}
+		}
+	}
+
+	res.LangCode = v2.LangCode
+	res.Profiles = resProfiles
+	res.Syncs = resSyncs
+	res.DefaultConcurrency = v2.DefaultConcurrency
+	res.RetryPolicy = retryPolicy
+
+	return res, nil
 }
 
 // SerializeToJson serializes a config to the given writer.
 func SerializeToJson(config *config.Config, writer io.Writer) error {
-	res := V1{
-		Version:  Version1,
-		LangCode: config.LangCode,
-		Syncs:    make([]V1Sync, len(config.Syncs)),
-		Profiles: make([]V1OutputProfile, len(config.Profiles)),
+	res := V2{
+		Version:            Version2,
+		LangCode:           config.LangCode,
+		Syncs:              make([]V2Sync, len(config.Syncs)),
+		Profiles:           make([]V2OutputProfile, len(config.Profiles)),
+		DefaultConcurrency: config.DefaultConcurrency,
+		RetryPolicy: &V2RetryPolicy{
+			MaxRetries:      config.RetryPolicy.MaxRetries,
+			BackoffMs:       config.RetryPolicy.Backoff.Milliseconds(),
+			RetryableErrors: make([]string, len(config.RetryPolicy.RetryableErrors)),
+		},
+	}
+
+	for i, class := range config.RetryPolicy.RetryableErrors {
+		res.RetryPolicy.RetryableErrors[i] = string(class)
 	}
 
 	for i, sync := range config.Syncs {
-		res.Syncs[i] = V1Sync{
+		res.Syncs[i] = V2Sync{
 			Name:               sync.Name,
 			SourceDir:          sync.SourceDir,
 			DestDir:            sync.DestDir,
 			ProfileName:        sync.Profile.Name,
 			EscapeFilenames:    sync.EscapeFilenames,
 			ReencodeSameFormat: sync.ReencodeSameFormat,
+			SmartTranscode:     sync.SmartTranscode,
+			Mirror:             sync.Mirror,
+			Concurrency:        sync.Concurrency,
+			LowPriority:        sync.LowPriority,
+			ConversionRules:    make([]V2ConversionRule, len(sync.ConversionRules)),
+			FilterRules:        make([]V2FilterRule, len(sync.FilterRules)),
+			TagRules:           make([]V2TagRule, len(sync.TagRules)),
+			FilePolicy:         int(sync.FilePolicy),
+			SidecarExtensions:  sync.SidecarExtensions,
+			EmbedFolderArtwork: sync.EmbedFolderArtwork,
+			ArtworkFilenames:   sync.ArtworkFilenames,
+		}
+
+		for j, rule := range sync.ConversionRules {
+			res.Syncs[i].ConversionRules[j] = V2ConversionRule{
+				Codecs:        rule.Codecs,
+				Extensions:    rule.Extensions,
+				MinSampleRate: rule.MinSampleRate,
+				MaxSampleRate: rule.MaxSampleRate,
+				MinBitDepth:   rule.MinBitDepth,
+				MaxBitDepth:   rule.MaxBitDepth,
+				Action:        int(rule.Action),
+			}
+			if rule.Profile != nil {
+				res.Syncs[i].ConversionRules[j].ProfileName = rule.Profile.Name
+			}
+		}
+
+		for j, rule := range sync.FilterRules {
+			res.Syncs[i].FilterRules[j] = V2FilterRule{
+				Exclude: rule.Exclude,
+				Pattern: rule.Pattern,
+			}
 		}
+
+		for j, rule := range sync.TagRules {
+			res.Syncs[i].TagRules[j] = V2TagRule{
+				Action:  int(rule.Action),
+				Tag:     rule.Tag,
+				Value:   rule.Value,
+				Source:  rule.Source,
+				Pattern: rule.Pattern,
+			}
+		}
+	}
+
+	// Sorted so that saving an unchanged config doesn't reorder it
+	for _, id := range slices.Sorted(maps.Keys(config.CustomFormats)) {
+		format := config.CustomFormats[id]
+		res.CustomFormats = append(res.CustomFormats, V2OutputFormat{
+			Id:               id,
+			Name:             format.Name,
+			Extension:        format.Extension,
+			FfmpegEncoder:    format.FfmpegEncoder,
+			IsLossless:       format.IsLossless,
+			SupportsMetadata: format.SupportsMetadata,
+			TagStyle:         int(format.TagStyle),
+			SupportsArtwork:  format.SupportsArtwork,
+			SuggestedBitrate: format.SuggestedBitrate,
+			CodecNames:       format.CodecNames,
+			Containers:       format.Containers,
+			MaxChannels:      format.MaxChannels,
+		})
 	}
 
 	for i, profile := range config.Profiles {
-		formatId, ok := profile.OutputFormat.GetId()
+		formatId, ok := config.GetOutputFormatId(profile.OutputFormat)
 		if !ok {
 			return ErrUnknownFormat
 		}
 
-		res.Profiles[i] = V1OutputProfile{
-			Name:           profile.Name,
-			OutputFormatId: formatId,
-			Bitrate:        profile.Bitrate,
+		res.Profiles[i] = V2OutputProfile{
+			Name:             profile.Name,
+			OutputFormatId:   formatId,
+			EncodingMode:     int(profile.EncodingMode),
+			Bitrate:          profile.Bitrate,
+			Quality:          profile.Quality,
+			MaxSampleRate:    profile.MaxSampleRate,
+			MaxBitDepth:      profile.MaxBitDepth,
+			DitherMethod:     profile.DitherMethod,
+			ChannelPolicy:    int(profile.ChannelPolicy),
+			Id3Version:       profile.Id3Version,
+			ArtworkPolicy:    int(profile.ArtworkPolicy),
+			MaxArtworkSize:   profile.MaxArtworkSize,
+			ArtworkQuality:   profile.ArtworkQuality,
+			ExtraArgs:        profile.ExtraArgs,
+			AudioFilter:      profile.AudioFilter,
+			CompatibleCodecs: profile.CompatibleCodecs,
 		}
 	}
 
@@ -0,0 +1,419 @@
+package json
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/termermc/your-loss-sync/config"
+)
+
+// v1Minimal is a version 1 config with every field that version 1 has.
+const v1Minimal = `{
+	"version": 1,
+	"langCode": "es-419",
+	"profiles": [
+		{"name": "Phone", "outputFormatId": 0, "bitrate": 192000},
+		{"name": "Lossless", "outputFormatId": 1, "bitrate": 0},
+		{"name": "Small", "outputFormatId": 3, "bitrate": 96000},
+		{"name": "Raw", "outputFormatId": 2, "bitrate": 0}
+	],
+	"syncs": [
+		{
+			"name": "Phone",
+			"sourceDir": "/music",
+			"destDir": "/phone/Music",
+			"profileName": "Phone",
+			"escapeFilenames": true,
+			"reencodeSameFormat": true
+		}
+	]
+}`
+
+// v2Full is a version 2 config with every field that version 2 has.
+const v2Full = `{
+	"version": 2,
+	"langCode": "zh-cn",
+	"defaultConcurrency": 3,
+	"retryPolicy": {"maxRetries": 5, "backoffMs": 1500, "retryableErrors": ["io", "ffmpeg-failed"]},
+	"customFormats": [
+		{"id": 1000, "name": "MP2", "extension": "mp2", "ffmpegEncoder": "mp2", "isLossless": false, "supportsMetadata": true, "tagStyle": 3, "supportsArtwork": false, "suggestedBitrate": 256000, "codecNames": ["mp2"], "containers": ["mp3"], "maxChannels": 2}
+	],
+	"profiles": [
+		{"name": "Car", "outputFormatId": 7, "encodingMode": 1, "bitrate": 160000, "quality": 0, "maxSampleRate": 0, "maxBitDepth": 0, "ditherMethod": "", "channelPolicy": 2, "id3Version": 0, "extraArgs": null, "audioFilter": "", "artworkPolicy": 0, "maxArtworkSize": 0, "artworkQuality": 0, "compatibleCodecs": ["mp3", "aac"]},
+		{"name": "Archive", "outputFormatId": 8, "encodingMode": 3, "bitrate": 0, "quality": 3, "maxSampleRate": 48000, "maxBitDepth": 16, "ditherMethod": "shibata", "channelPolicy": 0, "id3Version": 0, "extraArgs": ["-threads", "2"], "audioFilter": "volume=0.5", "artworkPolicy": 0, "maxArtworkSize": 0, "artworkQuality": 0, "compatibleCodecs": null},
+		{"name": "Old Player", "outputFormatId": 0, "encodingMode": 2, "bitrate": 0, "quality": 2, "maxSampleRate": 0, "maxBitDepth": 0, "ditherMethod": "", "channelPolicy": 0, "id3Version": 3, "extraArgs": null, "audioFilter": "", "artworkPolicy": 1, "maxArtworkSize": 500, "artworkQuality": 80, "compatibleCodecs": null},
+		{"name": "Radio", "outputFormatId": 1000, "encodingMode": 0, "bitrate": 256000, "quality": 0, "maxSampleRate": 0, "maxBitDepth": 0, "ditherMethod": "", "channelPolicy": 0, "id3Version": 0, "extraArgs": null, "audioFilter": "", "artworkPolicy": 2, "maxArtworkSize": 0, "artworkQuality": 0, "compatibleCodecs": null}
+	],
+	"syncs": [
+		{
+			"name": "Car",
+			"sourceDir": "/music",
+			"destDir": "/media/usb",
+			"profileName": "Car",
+			"escapeFilenames": false,
+			"reencodeSameFormat": true,
+			"smartTranscode": true,
+			"mirror": true,
+			"concurrency": 2,
+			"lowPriority": true,
+			"conversionRules": [
+				{"codecs": ["flac"], "extensions": [], "minSampleRate": 88200, "maxSampleRate": 0, "minBitDepth": 0, "maxBitDepth": 0, "action": 0, "profileName": "Archive"},
+				{"codecs": [], "extensions": ["wv"], "minSampleRate": 0, "maxSampleRate": 0, "minBitDepth": 0, "maxBitDepth": 0, "action": 1, "profileName": ""},
+				{"codecs": ["mp3"], "extensions": [], "minSampleRate": 0, "maxSampleRate": 0, "minBitDepth": 0, "maxBitDepth": 0, "action": 2, "profileName": ""}
+			],
+			"filterRules": [
+				{"exclude": false, "pattern": "Keep/**"},
+				{"exclude": true, "pattern": "*.log"}
+			],
+			"tagRules": [
+				{"action": 4, "tag": "album_artist", "value": "", "source": "artist", "pattern": ""},
+				{"action": 3, "tag": "title", "value": "", "source": "", "pattern": "\\s*\\(Remastered\\)$"}
+			],
+			"filePolicy": 2,
+			"sidecarExtensions": ["jpg", "lrc"],
+			"embedFolderArtwork": true,
+			"artworkFilenames": ["folder.jpg", "cover.png"]
+		}
+	]
+}`
+
+// deserialize deserializes a JSON config, failing the test if it is not valid.
+func deserialize(t *testing.T, str string) *config.Config {
+	t.Helper()
+
+	cfg, err := DeserializeFromJson(strings.NewReader(str))
+	if err != nil {
+		t.Fatalf("DeserializeFromJson() error = %v", err)
+	}
+
+	return cfg
+}
+
+// assertRoundTrip checks that serializing a config and reading it back doesn't lose anything.
+func assertRoundTrip(t *testing.T, cfg *config.Config) {
+	t.Helper()
+
+	var first bytes.Buffer
+	if err := SerializeToJson(cfg, &first); err != nil {
+		t.Fatalf("SerializeToJson() error = %v", err)
+	}
+	reread := deserialize(t, first.String())
+
+	var second bytes.Buffer
+	if err := SerializeToJson(reread, &second); err != nil {
+		t.Fatalf("SerializeToJson() error = %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("config changed after being written and read back:\n%s\n%s", first.String(), second.String())
+	}
+}
+
+func TestUpgradeV1Minimal(t *testing.T) {
+	cfg := deserialize(t, v1Minimal)
+
+	if cfg.LangCode != "es-419" {
+		t.Errorf("LangCode = %q", cfg.LangCode)
+	}
+	if cfg.DefaultConcurrency != 0 {
+		t.Errorf("DefaultConcurrency = %d", cfg.DefaultConcurrency)
+	}
+	if len(cfg.CustomFormats) != 0 {
+		t.Errorf("CustomFormats = %v", cfg.CustomFormats)
+	}
+
+	// Configs without a retry policy get the default one
+	defaultRetry := config.DefaultRetryPolicy()
+	if cfg.RetryPolicy.MaxRetries != defaultRetry.MaxRetries || cfg.RetryPolicy.Backoff != defaultRetry.Backoff ||
+		!slices.Equal(cfg.RetryPolicy.RetryableErrors, defaultRetry.RetryableErrors) {
+		t.Errorf("RetryPolicy = %+v, want the default %+v", cfg.RetryPolicy, defaultRetry)
+	}
+
+	// Profiles get the encoding mode and quality that their format was encoded with before encoding modes existed
+	wantProfiles := []config.OutputProfile{
+		{Name: "Phone", OutputFormat: config.SupportedOutputFormats[0], EncodingMode: config.EncodingCbr, Bitrate: 192000},
+		{Name: "Lossless", OutputFormat: config.SupportedOutputFormats[1], EncodingMode: config.EncodingCompression, Quality: 5},
+		{Name: "Small", OutputFormat: config.SupportedOutputFormats[3], EncodingMode: config.EncodingVbr, Bitrate: 96000},
+		{Name: "Raw", OutputFormat: config.SupportedOutputFormats[2], EncodingMode: config.EncodingCbr},
+	}
+	if len(cfg.Profiles) != len(wantProfiles) {
+		t.Fatalf("got %d profiles, want %d", len(cfg.Profiles), len(wantProfiles))
+	}
+	for i, want := range wantProfiles {
+		got := cfg.Profiles[i]
+		if got.Fingerprint() != want.Fingerprint() || got.Name != want.Name || got.OutputFormat.Name != want.OutputFormat.Name ||
+			got.EncodingMode != want.EncodingMode || got.Bitrate != want.Bitrate || got.Quality != want.Quality {
+			t.Errorf("profile %d = %+v, want %+v", i, *got, want)
+		}
+
+		// Options added in version 2 keep their defaults
+		if got.MaxSampleRate != 0 || got.MaxBitDepth != 0 || got.DitherMethod != "" || got.ChannelPolicy != config.ChannelPolicyPreserve ||
+			got.GetId3Version() != config.DefaultId3Version || got.ArtworkPolicy != config.ArtworkCopy ||
+			got.MaxArtworkSize != 0 || got.ArtworkQuality != 0 || got.ExtraArgs != nil || got.AudioFilter != "" ||
+			got.CompatibleCodecs != nil {
+			t.Errorf("profile %d has non-default version 2 options: %+v", i, *got)
+		}
+	}
+
+	if len(cfg.Syncs) != 1 {
+		t.Fatalf("got %d syncs, want 1", len(cfg.Syncs))
+	}
+	sync := cfg.Syncs[0]
+	if sync.Name != "Phone" || sync.SourceDir != "/music" || sync.DestDir != "/phone/Music" || sync.Profile != cfg.Profiles[0] ||
+		!sync.EscapeFilenames || !sync.ReencodeSameFormat {
+		t.Errorf("sync = %+v", *sync)
+	}
+
+	// Options added in version 2 keep their defaults
+	if sync.SmartTranscode || sync.Mirror || sync.Concurrency != 0 || sync.LowPriority || len(sync.ConversionRules) != 0 ||
+		len(sync.FilterRules) != 0 || len(sync.TagRules) != 0 || sync.FilePolicy != config.FilePolicyCopyAll ||
+		sync.EmbedFolderArtwork {
+		t.Errorf("sync has non-default options: %+v", *sync)
+	}
+	if !slices.Equal(sync.GetSidecarExtensions(), config.DefaultSidecarExtensions) {
+		t.Errorf("GetSidecarExtensions() = %v, want the defaults", sync.GetSidecarExtensions())
+	}
+	if !slices.Equal(sync.GetArtworkFilenames(), config.DefaultArtworkFilenames) {
+		t.Errorf("GetArtworkFilenames() = %v, want the defaults", sync.GetArtworkFilenames())
+	}
+
+	assertRoundTrip(t, cfg)
+}
+
+func TestDeserializeV2Full(t *testing.T) {
+	cfg := deserialize(t, v2Full)
+
+	if cfg.LangCode != "zh-cn" || cfg.DefaultConcurrency != 3 {
+		t.Errorf("LangCode = %q, DefaultConcurrency = %d", cfg.LangCode, cfg.DefaultConcurrency)
+	}
+
+	wantRetry := config.RetryPolicy{
+		MaxRetries:      5,
+		Backoff:         1500 * time.Millisecond,
+		RetryableErrors: []config.RetryErrorClass{config.RetryErrorIo, config.RetryErrorFfmpegFailed},
+	}
+	if cfg.RetryPolicy.MaxRetries != wantRetry.MaxRetries || cfg.RetryPolicy.Backoff != wantRetry.Backoff ||
+		!slices.Equal(cfg.RetryPolicy.RetryableErrors, wantRetry.RetryableErrors) {
+		t.Errorf("RetryPolicy = %+v, want %+v", cfg.RetryPolicy, wantRetry)
+	}
+
+	mp2, ok := cfg.CustomFormats[1000]
+	if !ok || mp2.Name != "MP2" || mp2.Extension != "mp2" || mp2.FfmpegEncoder != "mp2" || mp2.IsLossless ||
+		mp2.TagStyle != config.TagStyleId3 || mp2.SuggestedBitrate != 256000 || !slices.Equal(mp2.CodecNames, []string{"mp2"}) ||
+		mp2.MaxChannels != 2 {
+		t.Errorf("CustomFormats = %+v", cfg.CustomFormats)
+	}
+
+	if len(cfg.Profiles) != 4 {
+		t.Fatalf("got %d profiles, want 4", len(cfg.Profiles))
+	}
+	car, archive, oldPlayer, radio := cfg.Profiles[0], cfg.Profiles[1], cfg.Profiles[2], cfg.Profiles[3]
+	if car.OutputFormat.Name != "Ogg Vorbis" || car.EncodingMode != config.EncodingAbr || car.Bitrate != 160000 ||
+		car.ChannelPolicy != config.ChannelPolicyMono || !slices.Equal(car.CompatibleCodecs, []string{"mp3", "aac"}) {
+		t.Errorf("Car profile = %+v", *car)
+	}
+	if archive.OutputFormat.Name != "WavPack" || archive.EncodingMode != config.EncodingCompression || archive.Quality != 3 ||
+		archive.MaxSampleRate != 48000 || archive.MaxBitDepth != 16 || archive.DitherMethod != "shibata" ||
+		!slices.Equal(archive.ExtraArgs, []string{"-threads", "2"}) || archive.AudioFilter != "volume=0.5" {
+		t.Errorf("Archive profile = %+v", *archive)
+	}
+	if oldPlayer.EncodingMode != config.EncodingVbr || oldPlayer.Quality != 2 || oldPlayer.GetId3Version() != 3 ||
+		oldPlayer.ArtworkPolicy != config.ArtworkJpeg || oldPlayer.MaxArtworkSize != 500 || oldPlayer.ArtworkQuality != 80 {
+		t.Errorf("Old Player profile = %+v", *oldPlayer)
+	}
+	if radio.OutputFormat.Name != "MP2" || radio.Bitrate != 256000 || radio.ArtworkPolicy != config.ArtworkStrip {
+		t.Errorf("Radio profile = %+v", *radio)
+	}
+
+	if len(cfg.Syncs) != 1 {
+		t.Fatalf("got %d syncs, want 1", len(cfg.Syncs))
+	}
+	sync := cfg.Syncs[0]
+	if sync.Name != "Car" || sync.SourceDir != "/music" || sync.DestDir != "/media/usb" || sync.Profile != car ||
+		sync.EscapeFilenames || !sync.ReencodeSameFormat || !sync.SmartTranscode || !sync.Mirror || sync.Concurrency != 2 ||
+		!sync.LowPriority {
+		t.Errorf("sync = %+v", *sync)
+	}
+
+	if len(sync.ConversionRules) != 3 {
+		t.Fatalf("got %d conversion rules, want 3", len(sync.ConversionRules))
+	}
+	if rule := sync.ConversionRules[0]; rule.Action != config.ConversionTranscode || rule.Profile != archive ||
+		!slices.Equal(rule.Codecs, []string{"flac"}) || rule.MinSampleRate != 88200 {
+		t.Errorf("conversion rule 0 = %+v", rule)
+	}
+	if rule := sync.ConversionRules[1]; rule.Action != config.ConversionCopy || rule.Profile != nil ||
+		!slices.Equal(rule.Extensions, []string{"wv"}) {
+		t.Errorf("conversion rule 1 = %+v", rule)
+	}
+	if rule := sync.ConversionRules[2]; rule.Action != config.ConversionSkip || rule.Profile != nil {
+		t.Errorf("conversion rule 2 = %+v", rule)
+	}
+
+	wantFilters := []config.FilterRule{{Exclude: false, Pattern: "Keep/**"}, {Exclude: true, Pattern: "*.log"}}
+	if !slices.Equal(sync.FilterRules, wantFilters) {
+		t.Errorf("FilterRules = %+v, want %+v", sync.FilterRules, wantFilters)
+	}
+
+	tagRules := make([]string, len(sync.TagRules))
+	for i, rule := range sync.TagRules {
+		tagRules[i] = rule.String()
+	}
+	wantTagRules := []string{"fallback album_artist from artist", `replace title \s*\(Remastered\)$ =>`}
+	if !slices.Equal(tagRules, wantTagRules) {
+		t.Errorf("TagRules = %q, want %q", tagRules, wantTagRules)
+	}
+
+	// Loaded tag rules are compiled so that they can be applied
+	if re := sync.TagRules[1].Regexp(); re == nil || re.ReplaceAllString("Song (Remastered)", "") != "Song" {
+		t.Errorf("tag rule pattern was not compiled")
+	}
+
+	if sync.FilePolicy != config.FilePolicyAudioAndSidecars || !slices.Equal(sync.SidecarExtensions, []string{"jpg", "lrc"}) {
+		t.Errorf("FilePolicy = %d, SidecarExtensions = %v", sync.FilePolicy, sync.SidecarExtensions)
+	}
+	if !sync.EmbedFolderArtwork || !slices.Equal(sync.ArtworkFilenames, []string{"folder.jpg", "cover.png"}) {
+		t.Errorf("EmbedFolderArtwork = %v, ArtworkFilenames = %v", sync.EmbedFolderArtwork, sync.ArtworkFilenames)
+	}
+
+	assertRoundTrip(t, cfg)
+}
+
+func TestUpgradeV1Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+		want error
+	}{
+		{
+			name: "unknown format",
+			str:  `{"version": 1, "profiles": [{"name": "X", "outputFormatId": 999}], "syncs": []}`,
+			want: ErrUnknownFormat,
+		},
+		{
+			name: "unknown profile",
+			str:  `{"version": 1, "profiles": [], "syncs": [{"name": "X", "profileName": "Missing"}]}`,
+			want: ErrUnknownProfile,
+		},
+		{
+			name: "unsupported version",
+			str:  `{"version": 99}`,
+			want: ErrUnsupportedVersion,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DeserializeFromJson(strings.NewReader(test.str)); err != test.want {
+				t.Errorf("DeserializeFromJson() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+// v2WithSync returns a version 2 config with a single MP3 profile and a sync with the specified JSON fields.
+func v2WithSync(syncFields string) string {
+	return `{"version": 2, "profiles": [{"name": "X", "outputFormatId": 0, "bitrate": 320000}], "syncs": [
+		{"name": "X", "profileName": "X", ` + syncFields + `}
+	]}`
+}
+
+func TestDeserializeInvalidV2(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+		want error
+	}{
+		{
+			name: "unknown conversion action",
+			str:  v2WithSync(`"conversionRules": [{"codecs": ["flac"], "action": 3}]`),
+			want: config.ErrInvalidConversionRule,
+		},
+		{
+			name: "negative conversion action",
+			str:  v2WithSync(`"conversionRules": [{"codecs": ["flac"], "action": -1, "profileName": "X"}]`),
+			want: config.ErrInvalidConversionRule,
+		},
+		{
+			name: "conversion rule without a profile",
+			str:  v2WithSync(`"conversionRules": [{"codecs": ["flac"], "action": 0}]`),
+			want: ErrUnknownProfile,
+		},
+		{
+			name: "conversion rule with an unknown profile",
+			str:  v2WithSync(`"conversionRules": [{"codecs": ["flac"], "action": 0, "profileName": "Missing"}]`),
+			want: ErrUnknownProfile,
+		},
+		{
+			name: "invalid tag rule",
+			str:  v2WithSync(`"tagRules": [{"action": 3, "tag": "title", "pattern": "("}]`),
+			want: config.ErrInvalidTagRule,
+		},
+		{
+			name: "unknown tag action",
+			str:  v2WithSync(`"tagRules": [{"action": 5, "tag": "title", "value": "X"}]`),
+			want: config.ErrInvalidTagRule,
+		},
+		{
+			name: "unknown file policy",
+			str:  v2WithSync(`"filePolicy": 3`),
+			want: ErrInvalidFilePolicy,
+		},
+		{
+			name: "negative file policy",
+			str:  v2WithSync(`"filePolicy": -1`),
+			want: ErrInvalidFilePolicy,
+		},
+		{
+			name: "unknown channel policy",
+			str:  `{"version": 2, "profiles": [{"name": "X", "outputFormatId": 0, "bitrate": 320000, "channelPolicy": 3}], "syncs": []}`,
+			want: ErrInvalidChannelPolicy,
+		},
+		{
+			name: "unknown artwork policy",
+			str:  `{"version": 2, "profiles": [{"name": "X", "outputFormatId": 0, "bitrate": 320000, "artworkPolicy": 3}], "syncs": []}`,
+			want: ErrInvalidArtwork,
+		},
+		{
+			name: "duplicate custom format ID",
+			str: `{"version": 2, "profiles": [], "syncs": [], "customFormats": [
+				{"id": 1000, "name": "A", "extension": "a", "ffmpegEncoder": "a", "isLossless": true},
+				{"id": 1000, "name": "B", "extension": "b", "ffmpegEncoder": "b", "isLossless": true}
+			]}`,
+			want: config.ErrInvalidCustomFormat,
+		},
+		{
+			name: "custom format with a built-in ID",
+			str:  `{"version": 2, "profiles": [], "syncs": [], "customFormats": [{"id": 7, "name": "A", "extension": "a", "ffmpegEncoder": "a", "isLossless": true}]}`,
+			want: config.ErrInvalidCustomFormat,
+		},
+		{
+			name: "profile with an unknown custom format",
+			str:  `{"version": 2, "profiles": [{"name": "X", "outputFormatId": 1001}], "syncs": []}`,
+			want: ErrUnknownFormat,
+		},
+		{
+			name: "unknown retryable error",
+			str:  `{"version": 2, "profiles": [], "syncs": [], "retryPolicy": {"maxRetries": 1, "backoffMs": 0, "retryableErrors": ["io", "disk-full"]}}`,
+			want: ErrInvalidRetryPolicy,
+		},
+		{
+			name: "negative retry backoff",
+			str:  `{"version": 2, "profiles": [], "syncs": [], "retryPolicy": {"maxRetries": 1, "backoffMs": -1, "retryableErrors": []}}`,
+			want: ErrInvalidRetryPolicy,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DeserializeFromJson(strings.NewReader(test.str)); err != test.want {
+				t.Errorf("DeserializeFromJson() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	// The same config with a valid rule loads
+	deserialize(t, v2WithSync(`"conversionRules": [{"codecs": ["flac"], "action": 0, "profileName": "X"}]`))
+}
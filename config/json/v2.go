@@ -0,0 +1,110 @@
+package json
+
+// V2OutputProfile is the JSON format version 2 representation of an output profile.
+// Unlike V1OutputProfile, it has an encoding mode.
+type V2OutputProfile struct {
+	Name             string   `json:"name"`
+	OutputFormatId   int      `json:"outputFormatId"`
+	EncodingMode     int      `json:"encodingMode"`
+	Bitrate          uint     `json:"bitrate"`
+	Quality          float64  `json:"quality"`
+	MaxSampleRate    uint     `json:"maxSampleRate"`
+	MaxBitDepth      uint     `json:"maxBitDepth"`
+	DitherMethod     string   `json:"ditherMethod"`
+	ChannelPolicy    int      `json:"channelPolicy"`
+	Id3Version       uint     `json:"id3Version"`
+	ExtraArgs        []string `json:"extraArgs"`
+	AudioFilter      string   `json:"audioFilter"`
+	ArtworkPolicy    int      `json:"artworkPolicy"`
+	MaxArtworkSize   uint     `json:"maxArtworkSize"`
+	ArtworkQuality   uint     `json:"artworkQuality"`
+	CompatibleCodecs []string `json:"compatibleCodecs"`
+}
+
+// V2OutputFormat is the JSON format version 2 representation of a custom output format.
+type V2OutputFormat struct {
+	Id               int      `json:"id"`
+	Name             string   `json:"name"`
+	Extension        string   `json:"extension"`
+	FfmpegEncoder    string   `json:"ffmpegEncoder"`
+	IsLossless       bool     `json:"isLossless"`
+	SupportsMetadata bool     `json:"supportsMetadata"`
+	TagStyle         int      `json:"tagStyle"`
+	SupportsArtwork  bool     `json:"supportsArtwork"`
+	SuggestedBitrate uint     `json:"suggestedBitrate"`
+	CodecNames       []string `json:"codecNames"`
+	Containers       []string `json:"containers"`
+	MaxChannels      uint     `json:"maxChannels"`
+}
+
+// V2Sync is the JSON format version 2 representation of a sync.
+type V2Sync struct {
+	Name               string `json:"name"`
+	SourceDir          string `json:"sourceDir"`
+	DestDir            string `json:"destDir"`
+	ProfileName        string `json:"profileName"`
+	EscapeFilenames    bool   `json:"escapeFilenames"`
+	ReencodeSameFormat bool   `json:"reencodeSameFormat"`
+	SmartTranscode     bool   `json:"smartTranscode"`
+	Mirror             bool   `json:"mirror"`
+	Concurrency        uint   `json:"concurrency"`
+	LowPriority        bool   `json:"lowPriority"`
+
+	ConversionRules   []V2ConversionRule `json:"conversionRules"`
+	FilterRules       []V2FilterRule     `json:"filterRules"`
+	TagRules          []V2TagRule        `json:"tagRules"`
+	FilePolicy        int                `json:"filePolicy"`
+	SidecarExtensions []string           `json:"sidecarExtensions"`
+
+	EmbedFolderArtwork bool     `json:"embedFolderArtwork"`
+	ArtworkFilenames   []string `json:"artworkFilenames"` // Nil to use the defaults
+}
+
+// V2ConversionRule is the JSON format version 2 representation of a sync conversion rule.
+type V2ConversionRule struct {
+	Codecs        []string `json:"codecs"`
+	Extensions    []string `json:"extensions"`
+	MinSampleRate uint     `json:"minSampleRate"`
+	MaxSampleRate uint     `json:"maxSampleRate"`
+	MinBitDepth   uint     `json:"minBitDepth"`
+	MaxBitDepth   uint     `json:"maxBitDepth"`
+	Action        int      `json:"action"`
+	ProfileName   string   `json:"profileName"` // Only set for config.ConversionTranscode
+}
+
+// V2FilterRule is the JSON format version 2 representation of a sync filter rule.
+type V2FilterRule struct {
+	Exclude bool   `json:"exclude"`
+	Pattern string `json:"pattern"`
+}
+
+// V2TagRule is the JSON format version 2 representation of a sync tag rule.
+type V2TagRule struct {
+	Action  int    `json:"action"`
+	Tag     string `json:"tag"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`
+	Pattern string `json:"pattern"`
+}
+
+// V2RetryPolicy is the JSON format version 2 representation of a retry policy.
+type V2RetryPolicy struct {
+	MaxRetries      uint     `json:"maxRetries"`
+	BackoffMs       int64    `json:"backoffMs"`
+	RetryableErrors []string `json:"retryableErrors"`
+}
+
+// V2 is the JSON format version 2 representation of the application configuration.
+type V2 struct {
+	Version  int               `json:"version"` // Should be Version2
+	LangCode string            `json:"langCode"`
+	Syncs    []V2Sync          `json:"syncs"`
+	Profiles []V2OutputProfile `json:"profiles"`
+
+	CustomFormats []V2OutputFormat `json:"customFormats"`
+
+	DefaultConcurrency uint `json:"defaultConcurrency"`
+
+	// Nil in configs created before retry policies existed, in which case the default policy is used.
+	RetryPolicy *V2RetryPolicy `json:"retryPolicy"`
+}
@@ -1,5 +1,37 @@
 package config
 
+import (
+	"fmt"
+	"slices"
+)
+
+// TagStyle is how a container stores tags.
+type TagStyle int
+
+const (
+	// TagStyleFfmpeg passes tags to FFmpeg as they are, leaving it to the container to convert them.
+	// Used by custom formats that don't specify a style.
+	TagStyleFfmpeg TagStyle = iota
+
+	// TagStyleVorbis stores tags as Vorbis comments, such as in FLAC files.
+	TagStyleVorbis
+
+	// TagStyleOgg stores tags as Vorbis comments in the audio stream of an Ogg file.
+	TagStyleOgg
+
+	// TagStyleId3 stores tags as ID3v2 frames.
+	TagStyleId3
+
+	// TagStyleApe stores tags as APEv2 items.
+	TagStyleApe
+
+	// TagStyleMp4 stores tags as MP4 metadata atoms, which only cover a fixed set of tags.
+	TagStyleMp4
+
+	// TagStyleRiffInfo stores tags in a RIFF INFO chunk, which only covers a few basic tags.
+	TagStyleRiffInfo
+)
+
 // OutputFormat is an output format.
 type OutputFormat struct {
 	// Whether the format is lossless.
@@ -17,6 +49,13 @@ type OutputFormat struct {
 	// Whether the container used by the format supports metadata.
 	SupportsMetadata bool
 
+	// How tags are written to the format's container.
+	// Only applies to formats that support metadata.
+	TagStyle TagStyle
+
+	// The FFmpeg arguments that are needed for the format's container to write tags, such as "-write_id3v2", "1".
+	TagArgs []string
+
 	// Whether the container used by the format supports artwork.
 	SupportsArtwork bool
 
@@ -24,13 +63,73 @@ type OutputFormat struct {
 	// Does not apply to lossless formats.
 	// Should be a multiple of 1000.
 	SuggestedBitrate uint
+
+	// The FFprobe codec names of audio streams that are already in the format.
+	CodecNames []string
+
+	// The FFprobe format names of containers that are already in the format.
+	// FFprobe may report several comma-separated names for a single container, such as "mov,mp4,m4a,3gp,3g2,mj2".
+	Containers []string
+
+	// The FFmpeg sample formats used to encode each supported bit depth, keyed by bit depth.
+	// Only applies to lossless formats.
+	SampleFormats map[uint]string
+
+	// The FFmpeg encoders used to encode specific bit depths instead of FfmpegEncoder, keyed by bit depth.
+	// Used by formats that have a separate encoder for each bit depth, such as PCM formats.
+	BitDepthEncoders map[uint]string
+
+	// The maximum number of audio channels the format's encoder supports.
+	// Sources with more channels are downmixed to stereo.
+	// If 0, there is no limit.
+	MaxChannels uint
+
+	// The encoding modes the format supports, keyed by mode.
+	// If empty, the format is always encoded with DefaultEncodingMode.
+	EncodingModes map[EncodingMode]EncodingModeOptions
+
+	// The encoding mode that the format's encoder uses when no mode is selected.
+	// Profiles created before encoding modes existed are assumed to use it.
+	DefaultEncodingMode EncodingMode
+}
+
+// GetBitDepth returns the supported bit depth closest to the requested one.
+// The smallest supported bit depth that is at least the requested one is preferred, so that no precision is lost.
+// If the format does not support bit depths, 0 is returned.
+func (f OutputFormat) GetBitDepth(requested uint) uint {
+	var res uint
+	for depth := range f.SampleFormats {
+		switch {
+		case res == 0:
+			res = depth
+		case res < requested:
+			// Anything bigger is closer
+			res = max(res, depth)
+		case depth >= requested:
+			// Prefer the smallest depth that still fits
+			res = min(res, depth)
+		}
+	}
+
+	return res
+}
+
+// GetEncoder returns the FFmpeg encoder used to encode the specified bit depth.
+func (f OutputFormat) GetEncoder(bitDepth uint) string {
+	if encoder, ok := f.BitDepthEncoders[bitDepth]; ok {
+		return encoder
+	}
+
+	return f.FfmpegEncoder
 }
 
 // GetId returns the ID of the format.
+// Formats are identified by their names.
 // If the format is not in the supported formats map, the ID will be 0 and false will be returned.
+// Use Config.GetOutputFormatId to include custom formats.
 func (f OutputFormat) GetId() (int, bool) {
 	for id, format := range SupportedOutputFormats {
-		if format == f {
+		if format.Name == f.Name {
 			return id, true
 		}
 	}
@@ -38,6 +137,22 @@ func (f OutputFormat) GetId() (int, bool) {
 	return 0, false
 }
 
+// Accepts returns whether audio with the specified FFprobe codec name in a container with any of the specified FFprobe
+// format names is already in the format.
+func (f OutputFormat) Accepts(codecName string, containers []string) bool {
+	if !slices.Contains(f.CodecNames, codecName) {
+		return false
+	}
+
+	for _, container := range containers {
+		if slices.Contains(f.Containers, container) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SupportedOutputFormats is a mapping IDs to supported output formats.
 // A map is used instead of a slice to allow for reordering or removal without changing IDs.
 var SupportedOutputFormats = map[int]OutputFormat{
@@ -47,8 +162,18 @@ var SupportedOutputFormats = map[int]OutputFormat{
 		Extension:        "mp3",
 		FfmpegEncoder:    "libmp3lame",
 		SupportsMetadata: true,
+		TagStyle:         TagStyleId3,
 		SupportsArtwork:  true,
 		SuggestedBitrate: 320000,
+		CodecNames:       []string{"mp3"},
+		Containers:       []string{"mp3"},
+		MaxChannels:      2,
+		EncodingModes: map[EncodingMode]EncodingModeOptions{
+			EncodingCbr: {},
+			EncodingAbr: {Args: []string{"-abr", "1"}},
+			EncodingVbr: {QualityOption: "-q:a", MinQuality: 0, MaxQuality: 9, SuggestedQuality: 0},
+		},
+		DefaultEncodingMode: EncodingCbr,
 	},
 	1: {
 		IsLossless:       true,
@@ -56,8 +181,23 @@ var SupportedOutputFormats = map[int]OutputFormat{
 		Extension:        "flac",
 		FfmpegEncoder:    "flac",
 		SupportsMetadata: true,
+		TagStyle:         TagStyleVorbis,
 		SupportsArtwork:  true,
 		SuggestedBitrate: 0,
+		CodecNames:       []string{"flac"},
+		Containers:       []string{"flac"},
+		SampleFormats:    map[uint]string{16: "s16", 24: "s32"},
+		MaxChannels:      8,
+		EncodingModes: map[EncodingMode]EncodingModeOptions{
+			EncodingCompression: {
+				QualityOption:    "-compression_level",
+				MinQuality:       0,
+				MaxQuality:       12,
+				SuggestedQuality: 5,
+				IsIntegerQuality: true,
+			},
+		},
+		DefaultEncodingMode: EncodingCompression,
 	},
 	2: {
 		IsLossless:       true,
@@ -65,8 +205,13 @@ var SupportedOutputFormats = map[int]OutputFormat{
 		Extension:        "wav",
 		FfmpegEncoder:    "pcm_s16le",
 		SupportsMetadata: true,
+		TagStyle:         TagStyleRiffInfo,
 		SupportsArtwork:  false,
 		SuggestedBitrate: 0,
+		CodecNames:       []string{"pcm_s16le", "pcm_s24le"},
+		Containers:       []string{"wav"},
+		SampleFormats:    map[uint]string{16: "s16", 24: "s32"},
+		BitDepthEncoders: map[uint]string{16: "pcm_s16le", 24: "pcm_s24le"},
 	},
 	3: {
 		IsLossless:       false,
@@ -74,8 +219,18 @@ var SupportedOutputFormats = map[int]OutputFormat{
 		Extension:        "opus",
 		FfmpegEncoder:    "libopus",
 		SupportsMetadata: true,
+		TagStyle:         TagStyleOgg,
 		SupportsArtwork:  false,
 		SuggestedBitrate: 120000,
+		CodecNames:       []string{"opus"},
+		Containers:       []string{"ogg"},
+		MaxChannels:      8,
+		EncodingModes: map[EncodingMode]EncodingModeOptions{
+			EncodingCbr: {Args: []string{"-vbr", "off"}},
+			EncodingAbr: {Args: []string{"-vbr", "constrained"}},
+			EncodingVbr: {Args: []string{"-vbr", "on"}},
+		},
+		DefaultEncodingMode: EncodingVbr,
 	},
 	4: {
 		IsLossless:       false,
@@ -83,8 +238,17 @@ var SupportedOutputFormats = map[int]OutputFormat{
 		Extension:        "m4a",
 		FfmpegEncoder:    "aac",
 		SupportsMetadata: true,
+		TagStyle:         TagStyleMp4,
 		SupportsArtwork:  true,
 		SuggestedBitrate: 224000,
+		CodecNames:       []string{"aac"},
+		Containers:       []string{"mov", "mp4", "m4a"},
+		MaxChannels:      8,
+		EncodingModes: map[EncodingMode]EncodingModeOptions{
+			EncodingCbr: {},
+			EncodingVbr: {QualityOption: "-q:a", MinQuality: 0.1, MaxQuality: 2, SuggestedQuality: 1},
+		},
+		DefaultEncodingMode: EncodingCbr,
 	},
 	5: {
 		IsLossless:       true,
@@ -92,22 +256,102 @@ var SupportedOutputFormats = map[int]OutputFormat{
 		Extension:        "m4a",
 		FfmpegEncoder:    "alac",
 		SupportsMetadata: true,
+		TagStyle:         TagStyleMp4,
 		SupportsArtwork:  true,
 		SuggestedBitrate: 0,
+		CodecNames:       []string{"alac"},
+		Containers:       []string{"mov", "mp4", "m4a"},
+		SampleFormats:    map[uint]string{16: "s16p", 24: "s32p"},
+		MaxChannels:      8,
 	},
 	6: {
 		IsLossless:       true,
 		Name:             "AIFF",
 		Extension:        "aif",
 		FfmpegEncoder:    "pcm_s16be",
-		SupportsMetadata: true, // Limit, seems to only support title and comment
+		SupportsMetadata: true, // Only title and comment without an ID3 chunk, which TagArgs enables
+		TagStyle:         TagStyleId3,
+		TagArgs:          []string{"-write_id3v2", "1"},
+		SupportsArtwork:  false,
+		SuggestedBitrate: 0,
+		CodecNames:       []string{"pcm_s16be", "pcm_s24be"},
+		Containers:       []string{"aiff"},
+		SampleFormats:    map[uint]string{16: "s16", 24: "s32"},
+		BitDepthEncoders: map[uint]string{16: "pcm_s16be", 24: "pcm_s24be"},
+	},
+	7: {
+		IsLossless:       false,
+		Name:             "Ogg Vorbis",
+		Extension:        "ogg",
+		FfmpegEncoder:    "libvorbis",
+		SupportsMetadata: true,
+		TagStyle:         TagStyleOgg,
+		SupportsArtwork:  false,
+		SuggestedBitrate: 192000,
+		CodecNames:       []string{"vorbis"},
+		Containers:       []string{"ogg"},
+		MaxChannels:      8,
+		EncodingModes: map[EncodingMode]EncodingModeOptions{
+			EncodingAbr: {},
+			EncodingVbr: {QualityOption: "-q:a", MinQuality: 0, MaxQuality: 10, SuggestedQuality: 6},
+		},
+		DefaultEncodingMode: EncodingAbr,
+	},
+	8: {
+		IsLossless:       true,
+		Name:             "WavPack",
+		Extension:        "wv",
+		FfmpegEncoder:    "wavpack",
+		SupportsMetadata: true,
+		TagStyle:         TagStyleApe,
 		SupportsArtwork:  false,
 		SuggestedBitrate: 0,
+		CodecNames:       []string{"wavpack"},
+		Containers:       []string{"wv"},
+		SampleFormats:    map[uint]string{16: "s16p", 24: "s32p"},
+		EncodingModes: map[EncodingMode]EncodingModeOptions{
+			EncodingCompression: {
+				QualityOption:    "-compression_level",
+				MinQuality:       0,
+				MaxQuality:       8,
+				SuggestedQuality: 3,
+				IsIntegerQuality: true,
+			},
+		},
+		DefaultEncodingMode: EncodingCompression,
+	},
+	9: {
+		IsLossless:       true,
+		Name:             "WAV (24-bit)",
+		Extension:        "wav",
+		FfmpegEncoder:    "pcm_s24le",
+		SupportsMetadata: true,
+		TagStyle:         TagStyleRiffInfo,
+		SupportsArtwork:  false,
+		SuggestedBitrate: 0,
+		CodecNames:       []string{"pcm_s24le"},
+		Containers:       []string{"wav"},
+		SampleFormats:    map[uint]string{24: "s32"},
+	},
+	10: {
+		IsLossless:       true,
+		Name:             "AIFF (24-bit)",
+		Extension:        "aif",
+		FfmpegEncoder:    "pcm_s24be",
+		SupportsMetadata: true, // Only title and comment without an ID3 chunk, which TagArgs enables
+		TagStyle:         TagStyleId3,
+		TagArgs:          []string{"-write_id3v2", "1"},
+		SupportsArtwork:  false,
+		SuggestedBitrate: 0,
+		CodecNames:       []string{"pcm_s24be"},
+		Containers:       []string{"aiff"},
+		SampleFormats:    map[uint]string{24: "s32"},
 	},
 }
 
-// GetOutputFormat returns the output format with the specified name.
+// GetOutputFormat returns the built-in output format with the specified name.
 // If no format with the specified name exists, nil will be returned.
+// Use Config.GetOutputFormat to include custom formats.
 func GetOutputFormat(name string) *OutputFormat {
 	for _, format := range SupportedOutputFormats {
 		if format.Name == name {
@@ -130,7 +374,209 @@ type OutputProfile struct {
 	// The bitrate to use for the output format.
 	// Only applies to lossy formats.
 	// Should be a multiple of 1000.
+	// Does not apply to encoding modes that are controlled by Quality.
 	Bitrate uint
+
+	// The encoding mode.
+	// Must be one of the format's encoding modes.
+	// Default: OutputFormat.DefaultEncodingMode
+	EncodingMode EncodingMode
+
+	// The quality or compression level of the encoding mode.
+	// Only applies to encoding modes that are controlled by quality, and must be in the mode's range.
+	// Default: the mode's suggested quality
+	Quality float64
+
+	// The maximum sample rate of the output, in Hz.
+	// Sources with a higher sample rate are resampled to it.
+	// If 0, the source's sample rate is kept.
+	// Default: 0
+	MaxSampleRate uint
+
+	// The maximum bit depth of the output.
+	// Sources with a higher bit depth are reduced to it.
+	// If 0, the source's bit depth is kept.
+	// Only applies to lossless formats.
+	// Default: 0
+	MaxBitDepth uint
+
+	// The ID3v2 version of tags written to formats with TagStyleId3, either 3 or 4.
+	// Version 3 is more widely supported by older players and car stereos.
+	// If 0, DefaultId3Version is used.
+	// Default: 0
+	Id3Version uint
+
+	// The FFmpeg dither method used when reducing the bit depth, such as "triangular".
+	// If empty, no dither is applied.
+	// Only applies to lossless formats with a MaxBitDepth of at most MaxDitherBitDepth.
+	// Default: ""
+	DitherMethod string
+
+	// How the output's audio channels are laid out.
+	// Default: ChannelPolicyPreserve
+	ChannelPolicy ChannelPolicy
+
+	// The FFprobe names of lossy codecs that the device plays, such as "mp3".
+	// When a sync uses smart transcoding, lossy sources in these codecs may be copied as-is instead of being transcoded.
+	// Sources that are already in the output format are always considered playable.
+	// Default: nil
+	CompatibleCodecs []string
+
+	// Extra FFmpeg output options passed to the encoder, such as "-application", "audio".
+	// Must be valid according to ValidateExtraArgs.
+	// Default: nil
+	ExtraArgs []string
+
+	// An FFmpeg audio filter chain applied to outputs, such as "loudnorm".
+	// It is applied before resampling and downmixing.
+	// If not empty, it must be valid according to ValidateAudioFilter.
+	// Default: ""
+	AudioFilter string
+
+	// What happens to the artwork embedded into outputs.
+	// Only applies to formats that support artwork, other formats never have artwork.
+	// Default: ArtworkCopy
+	ArtworkPolicy ArtworkPolicy
+
+	// The maximum width and height of artwork in pixels.
+	// Larger artwork is scaled down.
+	// If 0, artwork is not scaled.
+	// Only applies to ArtworkJpeg.
+	// Default: 0
+	MaxArtworkSize uint
+
+	// The JPEG quality of artwork, from 1 to 100.
+	// If 0, DefaultArtworkQuality is used.
+	// Only applies to ArtworkJpeg.
+	// Default: 0
+	ArtworkQuality uint
+}
+
+// ChannelPolicy determines how many audio channels a profile's outputs have.
+type ChannelPolicy int
+
+const (
+	// ChannelPolicyPreserve keeps the source's channels.
+	// Sources with more channels than the output format supports are still downmixed to stereo.
+	ChannelPolicyPreserve ChannelPolicy = iota
+
+	// ChannelPolicyStereo downmixes sources with more than two channels to stereo.
+	ChannelPolicyStereo
+
+	// ChannelPolicyMono downmixes all sources to mono.
+	ChannelPolicyMono
+)
+
+// IsValid returns whether the channel policy exists.
+func (p ChannelPolicy) IsValid() bool {
+	return p >= ChannelPolicyPreserve && p <= ChannelPolicyMono
+}
+
+// MaxChannels returns the maximum number of channels the policy allows.
+// If the policy does not limit the number of channels, 0 is returned.
+func (p ChannelPolicy) MaxChannels() uint {
+	switch p {
+	case ChannelPolicyStereo:
+		return 2
+	case ChannelPolicyMono:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// DefaultId3Version is the ID3v2 version used by profiles that don't specify one.
+const DefaultId3Version = 4
+
+// GetId3Version returns the ID3v2 version of tags written by the profile.
+func (p *OutputProfile) GetId3Version() uint {
+	if p.Id3Version == 0 {
+		return DefaultId3Version
+	}
+
+	return p.Id3Version
+}
+
+// IsId3VersionValid returns whether the profile's ID3v2 version is 0, 3 or 4.
+func (p *OutputProfile) IsId3VersionValid() bool {
+	return p.Id3Version == 0 || p.Id3Version == 3 || p.Id3Version == 4
+}
+
+// MaxDitherBitDepth is the highest bit depth that dither is applied when reducing to.
+// The quantization noise of higher bit depths is far below what can be heard, so dithering them only adds noise.
+const MaxDitherBitDepth = 16
+
+// DitherMethods are the FFmpeg dither methods that can be used when reducing the bit depth.
+var DitherMethods = []string{"rectangular", "triangular", "triangular_hp", "lipshitz", "shibata", "low_shibata", "high_shibata", "f_weighted", "e_weighted", "modified_e_weighted", "improved_e_weighted"}
+
+// UsesDither returns whether the profile applies dither when reducing the bit depth of sources.
+// See MaxDitherBitDepth.
+func (p *OutputProfile) UsesDither() bool {
+	return p.OutputFormat.IsLossless && p.DitherMethod != "" && p.MaxBitDepth > 0 && p.MaxBitDepth <= MaxDitherBitDepth
+}
+
+// Fingerprint returns a string that identifies the profile's encoding settings.
+// Profiles that produce the same output have the same fingerprint, regardless of their names.
+// If a profile's fingerprint changes, files encoded with it are outdated.
+func (p *OutputProfile) Fingerprint() string {
+	// Custom formats are identified by their encoder and extension, which are included anyway
+	formatId, ok := p.OutputFormat.GetId()
+	if !ok {
+		formatId = -1
+	}
+
+	bitrate := p.Bitrate
+	if p.OutputFormat.IsLossless || p.GetEncodingModeOptions().UsesQuality() {
+		bitrate = 0
+	}
+
+	res := fmt.Sprintf("%d:%s:%s:%d", formatId, p.OutputFormat.Extension, p.OutputFormat.FfmpegEncoder, bitrate)
+
+	// The limits are only included if they differ from how outputs were encoded before they existed, so that files
+	// synced back then aren't re-encoded for nothing.
+	// Sample rates used to be kept, and so did bit depths, except for PCM formats, which were always 16-bit.
+	if p.MaxSampleRate > 0 {
+		res += fmt.Sprintf(":%d", p.MaxSampleRate)
+	}
+	if p.OutputFormat.IsLossless {
+		var oldBitDepth uint
+		if len(p.OutputFormat.BitDepthEncoders) > 0 {
+			oldBitDepth = 16
+		}
+		if p.MaxBitDepth != oldBitDepth {
+			res += fmt.Sprintf(":d%d", p.MaxBitDepth)
+		}
+	}
+	if p.UsesDither() {
+		res += ":dither=" + p.DitherMethod
+	}
+
+	if p.ChannelPolicy != ChannelPolicyPreserve {
+		res += fmt.Sprintf(":ch%d", p.ChannelPolicy)
+	}
+
+	if p.OutputFormat.TagStyle == TagStyleId3 && p.GetId3Version() != DefaultId3Version {
+		res += fmt.Sprintf(":id3v%d", p.GetId3Version())
+	}
+
+	if len(p.ExtraArgs) > 0 {
+		res += fmt.Sprintf(":args=%q", p.ExtraArgs)
+	}
+	if p.AudioFilter != "" {
+		res += fmt.Sprintf(":af=%q", p.AudioFilter)
+	}
+
+	// Encoding modes are only included if they differ from how the format was encoded before they existed
+	if p.EncodingMode != p.OutputFormat.DefaultEncodingMode || p.Quality != p.GetEncodingModeOptions().SuggestedQuality {
+		res += fmt.Sprintf(":m%d:%g", p.EncodingMode, p.Quality)
+	}
+
+	// Formats without artwork have it removed either way
+	if art := p.ArtworkFingerprint(); art != "" && p.OutputFormat.SupportsArtwork {
+		res += ":" + art
+	}
+
+	return res
 }
 
 // DefaultOutputProfiles is a list of default output profiles.
@@ -145,6 +591,8 @@ var DefaultOutputProfiles = []*OutputProfile{
 		Name:         "{{profile.default.flac.name}}",
 		OutputFormat: SupportedOutputFormats[1],
 		Bitrate:      0,
+		EncodingMode: EncodingCompression,
+		Quality:      5,
 	},
 	{
 		Name:         "{{profile.default.wav.name}}",
@@ -161,4 +609,28 @@ var DefaultOutputProfiles = []*OutputProfile{
 		OutputFormat: SupportedOutputFormats[5],
 		Bitrate:      0,
 	},
+	{
+		Name:         "{{profile.default.vorbis.name}}",
+		OutputFormat: SupportedOutputFormats[7],
+		Bitrate:      0,
+		EncodingMode: EncodingVbr,
+		Quality:      6,
+	},
+	{
+		Name:         "{{profile.default.wavpack.name}}",
+		OutputFormat: SupportedOutputFormats[8],
+		Bitrate:      0,
+		EncodingMode: EncodingCompression,
+		Quality:      3,
+	},
+	{
+		Name:         "{{profile.default.wav24.name}}",
+		OutputFormat: SupportedOutputFormats[9],
+		Bitrate:      0,
+	},
+	{
+		Name:         "{{profile.default.aiff24.name}}",
+		OutputFormat: SupportedOutputFormats[10],
+		Bitrate:      0,
+	},
 }
@@ -0,0 +1,157 @@
+package config
+
+import "testing"
+
+func TestOutputFormatAccepts(t *testing.T) {
+	mp4 := []string{"mov", "mp4", "m4a", "3gp", "3g2", "mj2"}
+
+	tests := []struct {
+		formatId   int
+		codec      string
+		containers []string
+		want       bool
+	}{
+		{0, "mp3", []string{"mp3"}, true},
+		{0, "mp3", []string{"wav"}, false},
+		{0, "mp3float", []string{"mp3"}, false},
+		{1, "flac", []string{"flac"}, true},
+		{1, "flac", []string{"ogg"}, false},
+		{2, "pcm_s16le", []string{"wav"}, true},
+		{2, "pcm_s24le", []string{"wav"}, true},
+		{2, "pcm_f32le", []string{"wav"}, false},
+		{2, "pcm_s16be", []string{"aiff"}, false},
+		{3, "opus", []string{"ogg"}, true},
+		{3, "libopus", []string{"ogg"}, false},
+		{3, "opus", []string{"matroska", "webm"}, false},
+		{4, "aac", mp4, true},
+		{4, "alac", mp4, false},
+		{4, "aac", []string{"aac"}, false},
+		{5, "alac", mp4, true},
+		{5, "aac", mp4, false},
+		{6, "pcm_s24be", []string{"aiff"}, true},
+		{6, "pcm_s16le", []string{"wav"}, false},
+		{7, "vorbis", []string{"ogg"}, true},
+		{7, "opus", []string{"ogg"}, false},
+		{8, "wavpack", []string{"wv"}, true},
+		{8, "flac", []string{"flac"}, false},
+		{0, "mp3", nil, false},
+	}
+
+	for _, test := range tests {
+		format := SupportedOutputFormats[test.formatId]
+		if got := format.Accepts(test.codec, test.containers); got != test.want {
+			t.Errorf("%s.Accepts(%q, %q) = %v, want %v", format.Name, test.codec, test.containers, got, test.want)
+		}
+	}
+}
+
+func TestOutputFormatGetId(t *testing.T) {
+	for id, format := range SupportedOutputFormats {
+		if got, ok := format.GetId(); !ok || got != id {
+			t.Errorf("%s.GetId() = %d, %v, want %d", format.Name, got, ok, id)
+		}
+	}
+
+	custom := OutputFormat{Name: "Custom"}
+	if _, ok := custom.GetId(); ok {
+		t.Error("GetId() of a custom format returned true")
+	}
+}
+
+func TestOutputProfileFingerprint(t *testing.T) {
+	flac := SupportedOutputFormats[1]
+	wav := SupportedOutputFormats[2]
+	mp3 := SupportedOutputFormats[0]
+
+	tests := []struct {
+		name string
+		prof OutputProfile
+		want string
+	}{
+		{"lossy", OutputProfile{OutputFormat: mp3, Bitrate: 320000}, "0:mp3:libmp3lame:320000"},
+		{"lossy with a sample rate limit", OutputProfile{OutputFormat: mp3, Bitrate: 320000, MaxSampleRate: 44100}, "0:mp3:libmp3lame:320000:44100"},
+		{"lossy ignores bit depth and dither", OutputProfile{OutputFormat: mp3, Bitrate: 320000, MaxBitDepth: 16, DitherMethod: "triangular"}, "0:mp3:libmp3lame:320000"},
+		{"lossless keeping the bit depth", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 5}, "1:flac:flac:0"},
+		{"lossless with limits", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 5, MaxSampleRate: 48000, MaxBitDepth: 16}, "1:flac:flac:0:48000:d16"},
+		{"lossless with dither", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 5, MaxBitDepth: 16, DitherMethod: "triangular"}, "1:flac:flac:0:d16:dither=triangular"},
+		{"dither does not apply to 24 bits", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 5, MaxBitDepth: 24, DitherMethod: "triangular"}, "1:flac:flac:0:d24"},
+		{"dither does not apply without a limit", OutputProfile{OutputFormat: flac, EncodingMode: EncodingCompression, Quality: 5, DitherMethod: "triangular"}, "1:flac:flac:0"},
+		{"PCM at 16 bits", OutputProfile{OutputFormat: wav, MaxBitDepth: 16}, "2:wav:pcm_s16le:0"},
+		{"PCM keeping the bit depth", OutputProfile{OutputFormat: wav}, "2:wav:pcm_s16le:0:d0"},
+	}
+
+	for _, test := range tests {
+		if got := test.prof.Fingerprint(); got != test.want {
+			t.Errorf("%s: Fingerprint() = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	// Names don't affect the output
+	named := OutputProfile{Name: "Named", OutputFormat: mp3, Bitrate: 320000}
+	if named.Fingerprint() != tests[0].prof.Fingerprint() {
+		t.Error("Fingerprint() depends on the profile's name")
+	}
+}
+
+func TestOutputProfileUsesDither(t *testing.T) {
+	tests := []struct {
+		formatId    int
+		maxBitDepth uint
+		dither      string
+		want        bool
+	}{
+		{1, 16, "triangular", true},
+		{1, 16, "", false},
+		{1, 24, "triangular", false},
+		{1, 0, "triangular", false},
+		{0, 16, "triangular", false},
+	}
+
+	for _, test := range tests {
+		prof := OutputProfile{OutputFormat: SupportedOutputFormats[test.formatId], MaxBitDepth: test.maxBitDepth, DitherMethod: test.dither}
+		if got := prof.UsesDither(); got != test.want {
+			t.Errorf("UsesDither() of %s with %d bits and %q = %v, want %v", prof.OutputFormat.Name, test.maxBitDepth,
+				test.dither, got, test.want)
+		}
+	}
+}
+
+func TestOutputFormatGetBitDepth(t *testing.T) {
+	flac := SupportedOutputFormats[1]
+
+	tests := []struct {
+		requested uint
+		want      uint
+	}{
+		{8, 16},
+		{16, 16},
+		{20, 24},
+		{24, 24},
+		{32, 24},
+	}
+
+	for _, test := range tests {
+		if got := flac.GetBitDepth(test.requested); got != test.want {
+			t.Errorf("GetBitDepth(%d) = %d, want %d", test.requested, got, test.want)
+		}
+	}
+
+	// Formats with a single bit depth always use it
+	for _, id := range []int{9, 10} {
+		if got := SupportedOutputFormats[id].GetBitDepth(16); got != 24 {
+			t.Errorf("GetBitDepth(16) of %s = %d, want 24", SupportedOutputFormats[id].Name, got)
+		}
+	}
+
+	if got := SupportedOutputFormats[0].GetBitDepth(16); got != 0 {
+		t.Errorf("GetBitDepth() of a lossy format = %d, want 0", got)
+	}
+}
+
+func TestChannelPolicyMaxChannels(t *testing.T) {
+	for policy, want := range map[ChannelPolicy]uint{ChannelPolicyPreserve: 0, ChannelPolicyStereo: 2, ChannelPolicyMono: 1, 99: 0} {
+		if got := policy.MaxChannels(); got != want {
+			t.Errorf("ChannelPolicy(%d).MaxChannels() = %d, want %d", policy, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"slices"
+	"time"
+)
+
+// maxRetryBackoff is the longest that a retry is delayed, no matter how many retries came before it.
+const maxRetryBackoff = time.Minute
+
+// RetryErrorClass is a class of errors that can cause a file to fail.
+type RetryErrorClass string
+
+const (
+	// RetryErrorIo is a filesystem error, such as a busy device or a failed rename.
+	RetryErrorIo RetryErrorClass = "io"
+
+	// RetryErrorFfmpegCrash is an FFmpeg process that crashed or was killed by a signal.
+	RetryErrorFfmpegCrash RetryErrorClass = "ffmpeg-crash"
+
+	// RetryErrorFfmpegFailed is an FFmpeg process that exited with an error code.
+	// This is usually caused by a broken source file, so retrying rarely helps.
+	RetryErrorFfmpegFailed RetryErrorClass = "ffmpeg-failed"
+)
+
+// AllRetryErrorClasses contains all retry error classes.
+var AllRetryErrorClasses = []RetryErrorClass{
+	RetryErrorIo,
+	RetryErrorFfmpegCrash,
+	RetryErrorFfmpegFailed,
+}
+
+// IsValid returns whether the error class exists.
+func (c RetryErrorClass) IsValid() bool {
+	return slices.Contains(AllRetryErrorClasses, c)
+}
+
+// RetryPolicy determines how files that failed to sync are retried.
+type RetryPolicy struct {
+	// The maximum number of times a failed file is retried.
+	// If 0, failed files are not retried.
+	// Default: 2
+	MaxRetries uint
+
+	// How long to wait before the first retry.
+	// The delay doubles with each following retry, up to a minute.
+	// Default: 2s
+	Backoff time.Duration
+
+	// The classes of errors that are retried.
+	// Default: RetryErrorIo, RetryErrorFfmpegCrash
+	RetryableErrors []RetryErrorClass
+}
+
+// DefaultRetryPolicy returns the default retry policy.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 2,
+		Backoff:    2 * time.Second,
+		RetryableErrors: []RetryErrorClass{
+			RetryErrorIo,
+			RetryErrorFfmpegCrash,
+		},
+	}
+}
+
+// IsRetryable returns whether errors of the specified class are retried.
+func (p *RetryPolicy) IsRetryable(class RetryErrorClass) bool {
+	return slices.Contains(p.RetryableErrors, class)
+}
+
+// GetBackoff returns how long to wait before the specified retry, starting at 1.
+func (p *RetryPolicy) GetBackoff(retry uint) time.Duration {
+	delay := p.Backoff
+	for i := uint(1); i < retry && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryBackoff)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyGetBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: 2 * time.Second}
+
+	tests := []struct {
+		retry uint
+		want  time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{5, 32 * time.Second},
+		{6, time.Minute},
+		{100, time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := policy.GetBackoff(test.retry); got != test.want {
+			t.Errorf("GetBackoff(%d) = %v, want %v", test.retry, got, test.want)
+		}
+	}
+
+	long := RetryPolicy{Backoff: 5 * time.Minute}
+	if got := long.GetBackoff(1); got != time.Minute {
+		t.Errorf("GetBackoff(1) with a long backoff = %v, want it capped at a minute", got)
+	}
+}
+
+func TestRetryPolicyIsRetryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	for class, want := range map[RetryErrorClass]bool{
+		RetryErrorIo:           true,
+		RetryErrorFfmpegCrash:  true,
+		RetryErrorFfmpegFailed: false,
+	} {
+		if got := policy.IsRetryable(class); got != want {
+			t.Errorf("IsRetryable(%q) = %v, want %v", class, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidTagRule is returned when a tag rule can't be parsed or has an invalid regular expression.
+var ErrInvalidTagRule = errors.New("{{config.error.invalid-tag-rule}}")
+
+// TagAction is what a tag rule does to a tag.
+type TagAction int
+
+const (
+	// TagSet sets the tag to the rule's value.
+	TagSet TagAction = iota
+
+	// TagRemove removes the tag.
+	TagRemove
+
+	// TagCopy sets the tag to the value of the rule's source tag.
+	// If the source tag is missing, the tag is left as-is.
+	TagCopy
+
+	// TagReplace replaces matches of the rule's pattern in the tag's value with the rule's value.
+	// If the result is empty, the tag is removed.
+	TagReplace
+
+	// TagFallback sets the tag to the rule's value, or to the value of its source tag if it has one, if the tag is
+	// missing or empty.
+	TagFallback
+)
+
+// TagRule rewrites a tag of the files synced by a sync.
+// Tags are referred to by name, ignoring case and anything but letters and digits, so "ALBUMARTIST" and "album_artist"
+// are the same tag.
+type TagRule struct {
+	// What the rule does.
+	Action TagAction
+
+	// The name of the tag to rewrite.
+	Tag string
+
+	// The value to set for TagSet and TagFallback, or the replacement for TagReplace.
+	// Replacements can refer to the pattern's groups, such as "$1".
+	Value string
+
+	// The name of the tag to copy the value from for TagCopy and TagFallback.
+	// Only one of Value and Source is used by TagFallback.
+	Source string
+
+	// The regular expression to match for TagReplace, using the syntax of the regexp package.
+	Pattern string
+
+	// The compiled Pattern.
+	// Set by Compile.
+	compiledPattern *regexp.Regexp
+}
+
+// tagNamePattern matches valid tag names.
+var tagNamePattern = regexp.MustCompile(`^[^\s=]+$`)
+
+// ParseTagRule parses a tag rule in one of the following forms:
+//
+//	set <tag> = <value>
+//	remove <tag>
+//	copy <tag> from <source tag>
+//	replace <tag> <pattern> => <replacement>
+//	fallback <tag> = <value>
+//	fallback <tag> from <source tag>
+//
+// Values and replacements are trimmed of surrounding whitespace, and the replacement may be empty.
+// The returned rule is compiled.
+// If the rule is malformed or its pattern is invalid, ErrInvalidTagRule is returned.
+func ParseTagRule(str string) (TagRule, error) {
+	actionStr, rest, _ := strings.Cut(strings.TrimSpace(str), " ")
+	tagName, args, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	args = strings.TrimSpace(args)
+
+	rule := TagRule{Tag: tagName}
+
+	// parseAssignment parses "= value" or "from source" into the rule
+	parseAssignment := func(allowSource bool) bool {
+		if value, ok := strings.CutPrefix(args, "="); ok {
+			rule.Value = strings.TrimSpace(value)
+			return rule.Value != ""
+		}
+		if source, ok := strings.CutPrefix(args, "from "); ok && allowSource {
+			rule.Source = strings.TrimSpace(source)
+			return true
+		}
+		return false
+	}
+
+	var ok bool
+	switch actionStr {
+	case "set":
+		rule.Action = TagSet
+		ok = parseAssignment(false)
+	case "remove":
+		rule.Action = TagRemove
+		ok = args == ""
+	case "copy":
+		rule.Action = TagCopy
+		ok = parseAssignment(true) && rule.Source != ""
+	case "replace":
+		rule.Action = TagReplace
+		var pattern string
+		pattern, rule.Value, ok = strings.Cut(args, "=>")
+		rule.Pattern = strings.TrimSpace(pattern)
+		rule.Value = strings.TrimSpace(rule.Value)
+	case "fallback":
+		rule.Action = TagFallback
+		ok = parseAssignment(true)
+	}
+
+	if !ok || rule.Compile() != nil {
+		return TagRule{}, ErrInvalidTagRule
+	}
+
+	return rule, nil
+}
+
+// String returns the rule in the form accepted by ParseTagRule.
+func (r TagRule) String() string {
+	switch r.Action {
+	case TagRemove:
+		return "remove " + r.Tag
+	case TagCopy:
+		return "copy " + r.Tag + " from " + r.Source
+	case TagReplace:
+		return strings.TrimSpace("replace " + r.Tag + " " + r.Pattern + " => " + r.Value)
+	case TagFallback:
+		if r.Source != "" {
+			return "fallback " + r.Tag + " from " + r.Source
+		}
+		return "fallback " + r.Tag + " = " + r.Value
+	default:
+		return "set " + r.Tag + " = " + r.Value
+	}
+}
+
+// Compile checks that the rule's tag names are valid, it has the values its action needs, and its pattern is a valid
+// regular expression, then compiles its pattern so that it doesn't need to be compiled for every file.
+// Rules that are not created by ParseTagRule must be compiled before they are applied.
+// If the rule is not valid, ErrInvalidTagRule is returned.
+func (r *TagRule) Compile() error {
+	if !r.isValid() {
+		return ErrInvalidTagRule
+	}
+
+	if r.Action == TagReplace {
+		var err error
+		r.compiledPattern, err = regexp.Compile(r.Pattern)
+		if err != nil {
+			return ErrInvalidTagRule
+		}
+	}
+
+	return nil
+}
+
+// isValid returns whether the rule's tag names are valid and it has the values its action needs.
+// The pattern is only checked to be non-empty.
+func (r *TagRule) isValid() bool {
+	if !tagNamePattern.MatchString(r.Tag) {
+		return false
+	}
+
+	switch r.Action {
+	case TagSet:
+		return r.Value != "" && !strings.Contains(r.Value, "\n")
+	case TagRemove:
+		return true
+	case TagCopy:
+		return tagNamePattern.MatchString(r.Source)
+	case TagReplace:
+		return r.Pattern != "" && !strings.Contains(r.Pattern, "=>") && !strings.Contains(r.Value, "\n")
+	case TagFallback:
+		if r.Source != "" {
+			return r.Value == "" && tagNamePattern.MatchString(r.Source)
+		}
+		return r.Value != "" && !strings.Contains(r.Value, "\n")
+	default:
+		return false
+	}
+}
+
+// Regexp returns the compiled pattern of a TagReplace rule.
+// The rule must have been compiled.
+func (r *TagRule) Regexp() *regexp.Regexp {
+	return r.compiledPattern
+}
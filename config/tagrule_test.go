@@ -0,0 +1,92 @@
+package config
+
+import "testing"
+
+func TestParseTagRule(t *testing.T) {
+	tests := []struct {
+		str  string
+		want TagRule
+	}{
+		{"set genre = Jazz Fusion", TagRule{Action: TagSet, Tag: "genre", Value: "Jazz Fusion"}},
+		{"  set  genre   =Jazz  ", TagRule{Action: TagSet, Tag: "genre", Value: "Jazz"}},
+		{"remove comment", TagRule{Action: TagRemove, Tag: "comment"}},
+		{"copy ALBUMARTIST from artist", TagRule{Action: TagCopy, Tag: "ALBUMARTIST", Source: "artist"}},
+		{`replace title \s*\(Remastered\)$ =>`, TagRule{Action: TagReplace, Tag: "title", Pattern: `\s*\(Remastered\)$`}},
+		{"replace artist ^(.+), (.+)$ => $2 $1", TagRule{Action: TagReplace, Tag: "artist", Pattern: "^(.+), (.+)$", Value: "$2 $1"}},
+		{"fallback album_artist from artist", TagRule{Action: TagFallback, Tag: "album_artist", Source: "artist"}},
+		{"fallback genre = Unknown", TagRule{Action: TagFallback, Tag: "genre", Value: "Unknown"}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTagRule(test.str)
+		if err != nil {
+			t.Errorf("ParseTagRule(%q) error = %v", test.str, err)
+			continue
+		}
+
+		// The compiled pattern is compared separately
+		if got.Action != test.want.Action || got.Tag != test.want.Tag || got.Value != test.want.Value ||
+			got.Source != test.want.Source || got.Pattern != test.want.Pattern {
+			t.Errorf("ParseTagRule(%q) = %+v, want %+v", test.str, got, test.want)
+		}
+		if got.Action == TagReplace && (got.Regexp() == nil || got.Regexp().String() != got.Pattern) {
+			t.Errorf("ParseTagRule(%q) did not compile its pattern", test.str)
+		}
+
+		reparsed, err := ParseTagRule(got.String())
+		if err != nil || reparsed.String() != got.String() {
+			t.Errorf("ParseTagRule(%q) does not round-trip through String %q: %v", test.str, got.String(), err)
+		}
+	}
+}
+
+func TestParseTagRuleInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"set",
+		"set genre",
+		"set genre =",
+		"set genre from artist",
+		"remove",
+		"remove comment now",
+		"copy album_artist",
+		"copy album_artist = x",
+		"copy album_artist from",
+		"replace title",
+		"replace title => x",
+		"replace title (unclosed => x",
+		"fallback genre",
+		"fallback genre =",
+		"rename title = x",
+		"set gen=re = x",
+	}
+
+	for _, str := range tests {
+		if rule, err := ParseTagRule(str); err != ErrInvalidTagRule {
+			t.Errorf("ParseTagRule(%q) = %+v, %v, want ErrInvalidTagRule", str, rule, err)
+		}
+	}
+}
+
+func TestTagRuleCompile(t *testing.T) {
+	rule := TagRule{Action: TagReplace, Tag: "title", Pattern: "^The "}
+	if err := rule.Compile(); err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if rule.Regexp() == nil || !rule.Regexp().MatchString("The End") {
+		t.Errorf("Compile() did not compile the pattern")
+	}
+
+	invalid := []TagRule{
+		{Action: TagReplace, Tag: "title", Pattern: "("},
+		{Action: TagReplace, Tag: "title"},
+		{Action: TagSet, Tag: "title", Value: "a\nb"},
+		{Action: TagFallback, Tag: "title", Value: "x", Source: "artist"},
+		{Action: TagAction(99), Tag: "title"},
+	}
+	for _, rule := range invalid {
+		if err := rule.Compile(); err != ErrInvalidTagRule {
+			t.Errorf("Compile(%+v) = %v, want ErrInvalidTagRule", rule, err)
+		}
+	}
+}
@@ -109,6 +109,9 @@ func (g Gui) Run() {
 		state, err := logic.Init()
 		checkErr(err)
 
+		// Mirror sync progress to stderr
+		state.Events.Subscribe(logic.NewLogWriter(os.Stderr, state.Locale))
+
 		g.State = state
 		g.initMainWindow()
 	}()
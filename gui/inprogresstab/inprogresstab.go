@@ -1,13 +1,18 @@
 package inprogresstab
 
 import (
+	"context"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 	"github.com/termermc/your-loss-sync/logic"
 	"math"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,32 +39,200 @@ func New(s *logic.AppState, parent fyne.Window) InProgressTab {
 		}
 	}()
 
+	// The number of log lines that were dropped since the last line made it into the log
+	var droppedLines atomic.Int64
+
+	s.Events.Subscribe(func(event logic.Event) {
+		// Queued and skipped files would flood the log, so only events about work being done are shown
+		if event.Type == logic.EventFileQueued || event.Type == logic.EventFileSkipped {
+			return
+		}
+
+		msg := event.Describe(s.Locale)
+		dropped := droppedLines.Swap(0)
+		if dropped > 0 {
+			msg = s.Locale.Tr("tab.progress.log-dropped", strconv.FormatInt(dropped, 10)) + "\n" + msg
+		}
+
+		// Events are emitted synchronously, so blocking here would stall the sync until the log catches up.
+		// Lines are dropped instead, and how many were dropped is logged once there is room again.
+		select {
+		case logOut <- msg:
+		default:
+			droppedLines.Add(dropped + 1)
+		}
+	})
+
 	statusLabel := widget.NewLabel("")
 	progressBar := widget.NewProgressBar()
 
+	// Cancels the running sync or preview, if any
+	var cancelSync context.CancelFunc
+
+	// Whether a preview is being planned.
+	// A preview can be cancelled like a sync, and no sync can be started while it runs.
+	var isPreviewing atomic.Bool
+
 	syncsSelector := widget.NewSelect([]string{}, func(_ string) {})
 	selectorScroll := container.NewScroll(syncsSelector)
 	selectorScroll.SetMinSize(fyne.NewSize(300, 40))
 	actionBtn := widget.NewButton("", func() {
-		if s.Progress.Sync.Load() == nil {
+		if s.Progress.Sync.Load() == nil && !isPreviewing.Load() {
 			syncConf := s.Config.GetSync(syncsSelector.Selected)
 
 			logMultilineLock.Lock()
 			logMultiline.SetText("")
 			logMultilineLock.Unlock()
-			go logic.StartSync(s, syncConf, logOut)
+
+			// Called from the sync's goroutine, so it can block until the user responds
+			confirmDelete := func(staleFiles []string) bool {
+				// Only list the first few files to keep the dialog a reasonable size
+				const maxListed = 10
+				listed := staleFiles
+				if len(listed) > maxListed {
+					listed = listed[:maxListed]
+				}
+				summary := strings.Join(listed, "\n")
+				if len(staleFiles) > maxListed {
+					summary += "\n" + s.Locale.Tr("tab.progress.stale-confirm.more", strconv.Itoa(len(staleFiles)-maxListed))
+				}
+
+				resChan := make(chan bool)
+				title := s.Locale.Tr("tab.progress.stale-confirm.title")
+				desc := s.Locale.Tr("tab.progress.stale-confirm.description", strconv.Itoa(len(staleFiles)), summary)
+				dialog.ShowConfirm(title, desc, func(b bool) {
+					resChan <- b
+				}, parent)
+
+				return <-resChan
+			}
+
+			var ctx context.Context
+			ctx, cancelSync = context.WithCancel(context.Background())
+			go logic.StartSync(ctx, s, syncConf, confirmDelete)
+		} else if cancelSync != nil {
+			cancelSync()
+		}
+	})
+
+	pauseBtn := widget.NewButton(s.Locale.Tr("tab.progress.pause"), func() {
+		if s.SyncStatus() == logic.SyncStatusPaused {
+			s.ResumeSync()
 		} else {
-			s.Progress.Sync.Store(nil)
+			s.PauseSync()
+		}
+	})
+	pauseBtn.Disable()
+
+	previewBtn := widget.NewButton(s.Locale.Tr("tab.progress.preview"), func() {
+		syncConf := s.Config.GetSync(syncsSelector.Selected)
+		if syncConf == nil || s.Progress.Sync.Load() != nil || !isPreviewing.CompareAndSwap(false, true) {
+			return
+		}
+
+		logMultilineLock.Lock()
+		logMultiline.SetText("")
+		logMultilineLock.Unlock()
+
+		var ctx context.Context
+		ctx, cancelSync = context.WithCancel(context.Background())
+		go func() {
+			defer isPreviewing.Store(false)
+
+			plan, err := logic.PlanSync(ctx, s, syncConf)
+			if err != nil {
+				// A cancelled preview is not an error
+				if ctx.Err() == nil {
+					dialog.ShowError(err, parent)
+				}
+				return
+			}
+
+			for _, action := range plan.Actions {
+				logOut <- action.Describe(s.Locale)
+			}
+
+			logOut <- s.Locale.Tr(
+				"tab.progress.preview-summary",
+				strconv.Itoa(plan.Count(logic.ActionTranscode)),
+				strconv.Itoa(plan.Count(logic.ActionCopy)),
+				strconv.Itoa(plan.Count(logic.ActionSkip)),
+				strconv.Itoa(plan.Count(logic.ActionDelete)),
+				strconv.Itoa(plan.Count(logic.ActionFail)),
+			)
+		}()
+	})
+
+	retryBtn := widget.NewButton(s.Locale.Tr("tab.progress.retry-failed"), func() {
+		report := s.Progress.Report.Load()
+		if report == nil || s.Progress.Sync.Load() != nil || isPreviewing.Load() {
+			return
+		}
+
+		syncConf := s.Config.GetSync(report.SyncName)
+		if syncConf == nil {
+			return
 		}
+
+		logMultilineLock.Lock()
+		logMultiline.SetText("")
+		logMultilineLock.Unlock()
+
+		var ctx context.Context
+		ctx, cancelSync = context.WithCancel(context.Background())
+		go logic.RetryFailed(ctx, s, syncConf, report)
 	})
+	retryBtn.Disable()
+
+	exportBtn := widget.NewButton(s.Locale.Tr("tab.progress.export-failures"), func() {
+		report := s.Progress.Report.Load()
+		if report == nil {
+			return
+		}
+
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			if writer == nil {
+				// Dialog was cancelled
+				return
+			}
+			defer func() {
+				_ = writer.Close()
+			}()
+
+			if strings.EqualFold(writer.URI().Extension(), ".csv") {
+				err = report.WriteCsv(writer)
+			} else {
+				err = report.WriteJson(writer)
+			}
+			if err != nil {
+				dialog.ShowError(err, parent)
+			}
+		}, parent)
+		saveDialog.SetFileName("failures.json")
+		saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".json", ".csv"}))
+		saveDialog.Show()
+	})
+	exportBtn.Disable()
 
 	// Periodically update status
 	go func() {
 		for {
 			time.Sleep(100 * time.Millisecond)
 
+			statusKey := "tab.progress.status-label"
+			switch s.SyncStatus() {
+			case logic.SyncStatusCancelled:
+				statusKey = "tab.progress.status-label-cancelled"
+			case logic.SyncStatusPaused:
+				statusKey = "tab.progress.status-label-paused"
+			}
+
 			statusLabel.SetText(s.Locale.Tr(
-				"tab.progress.status-label",
+				statusKey,
 				strconv.Itoa(int(s.Progress.Completed.Load())),
 				strconv.Itoa(int(s.Progress.Total.Load())),
 				strconv.Itoa(int(s.Progress.Failed.Load()))),
@@ -72,13 +245,43 @@ func New(s *logic.AppState, parent fyne.Window) InProgressTab {
 
 			progressBar.SetValue(percent)
 
-			if s.Progress.Sync.Load() == nil {
+			if isPreviewing.Load() {
+				syncsSelector.Disable()
+				previewBtn.Disable()
+				pauseBtn.Disable()
+				exportBtn.Disable()
+				retryBtn.Disable()
+				actionBtn.SetText(s.Locale.Tr("tab.progress.cancel"))
+			} else if s.Progress.Sync.Load() == nil {
 				syncsSelector.Enable()
+				previewBtn.Enable()
+				pauseBtn.Disable()
+				if report := s.Progress.Report.Load(); report != nil && report.Len() > 0 {
+					exportBtn.Enable()
+					if s.Config.GetSync(report.SyncName) != nil {
+						retryBtn.Enable()
+					} else {
+						retryBtn.Disable()
+					}
+				} else {
+					exportBtn.Disable()
+					retryBtn.Disable()
+				}
 				actionBtn.SetText(s.Locale.Tr("tab.progress.start"))
 			} else {
 				syncsSelector.Disable()
+				previewBtn.Disable()
+				pauseBtn.Enable()
+				exportBtn.Disable()
+				retryBtn.Disable()
 				actionBtn.SetText(s.Locale.Tr("tab.progress.cancel"))
 			}
+
+			if s.SyncStatus() == logic.SyncStatusPaused {
+				pauseBtn.SetText(s.Locale.Tr("tab.progress.resume"))
+			} else {
+				pauseBtn.SetText(s.Locale.Tr("tab.progress.pause"))
+			}
 		}
 	}()
 
@@ -86,6 +289,10 @@ func New(s *logic.AppState, parent fyne.Window) InProgressTab {
 		container.NewHBox(
 			selectorScroll,
 			actionBtn,
+			pauseBtn,
+			previewBtn,
+			retryBtn,
+			exportBtn,
 		),
 		statusLabel,
 		progressBar,
@@ -10,7 +10,11 @@ import (
 	"github.com/termermc/your-loss-sync/config"
 	ylwidget "github.com/termermc/your-loss-sync/gui/widget"
 	"github.com/termermc/your-loss-sync/logic"
+	"maps"
+	"slices"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 type ProfilesTab struct {
@@ -36,15 +40,7 @@ func New(s *logic.AppState, parent fyne.Window) ProfilesTab {
 				Label:     s.Config.Profiles[id].Name,
 				CanDelete: true,
 				OnDelete: func() {
-					inUse := false
-					for _, sync := range s.Config.Syncs {
-						if sync.Profile.Name == s.Config.Profiles[id].Name {
-							inUse = true
-							break
-						}
-					}
-
-					if inUse {
+					if s.Config.IsProfileInUse(s.Config.Profiles[id].Name) {
 						dialog.ShowError(errors.New(s.Locale.Tr("tab.profiles.error.in-use")), parent)
 						return
 					}
@@ -91,26 +87,169 @@ func New(s *logic.AppState, parent fyne.Window) ProfilesTab {
 	supportsArtworkCheck := widget.NewCheck(s.Locale.Tr("tab.profiles.form.supports-artwork"), func(_ bool) {})
 	supportsArtworkCheck.Disable()
 	bitrateEntry := widget.NewEntry()
-	formatNames := make([]string, 0, len(config.SupportedOutputFormats))
-	for _, format := range config.SupportedOutputFormats {
-		formatNames = append(formatNames, format.Name)
+	qualityEntry := widget.NewEntry()
+	encodingModeKeys := map[config.EncodingMode]string{
+		config.EncodingCbr:         "tab.profiles.form.encoding-mode.cbr",
+		config.EncodingAbr:         "tab.profiles.form.encoding-mode.abr",
+		config.EncodingVbr:         "tab.profiles.form.encoding-mode.vbr",
+		config.EncodingCompression: "tab.profiles.form.encoding-mode.compression",
+	}
+
+	// The selected format and its encoding modes, in the order they are listed in encodingModeSelector
+	var selectedFormat *config.OutputFormat
+	var formatModes []config.EncodingMode
+
+	var onEncodingModeSelect func()
+	encodingModeSelector := widget.NewSelect(nil, func(_ string) {
+		onEncodingModeSelect()
+	})
+	onEncodingModeSelect = func() {
+		var modeOpts config.EncodingModeOptions
+		if idx := encodingModeSelector.SelectedIndex(); idx >= 0 {
+			modeOpts = selectedFormat.EncodingModes[formatModes[idx]]
+		}
+
+		if modeOpts.UsesQuality() {
+			bitrateEntry.Disable()
+			bitrateEntry.SetText("")
+			qualityEntry.Enable()
+			qualityEntry.SetText(strconv.FormatFloat(modeOpts.SuggestedQuality, 'f', -1, 64))
+			qualityEntry.SetPlaceHolder(s.Locale.Tr(
+				"tab.profiles.form.quality.range",
+				strconv.FormatFloat(modeOpts.MinQuality, 'f', -1, 64),
+				strconv.FormatFloat(modeOpts.MaxQuality, 'f', -1, 64),
+			))
+		} else {
+			qualityEntry.Disable()
+			qualityEntry.SetText("")
+			qualityEntry.SetPlaceHolder("")
+			if !selectedFormat.IsLossless {
+				bitrateEntry.Enable()
+				if bitrateEntry.Text == "" {
+					bitrateEntry.SetText(strconv.Itoa(int(selectedFormat.SuggestedBitrate)))
+				}
+			}
+		}
+	}
+	maxSampleRateEntry := widget.NewEntry()
+	maxSampleRateEntry.SetPlaceHolder(s.Locale.Tr("tab.profiles.form.no-limit"))
+	bitDepthOptions := []string{
+		s.Locale.Tr("tab.profiles.form.bit-depth.source"),
+		s.Locale.Tr("tab.profiles.form.bit-depth.bits", "16"),
+		s.Locale.Tr("tab.profiles.form.bit-depth.bits", "24"),
+	}
+	bitDepthValues := []uint{0, 16, 24}
+	maxBitDepthSelector := widget.NewSelect(bitDepthOptions, func(_ string) {})
+	ditherOptions := append([]string{s.Locale.Tr("tab.profiles.form.dither.none")}, config.DitherMethods...)
+	ditherSelector := widget.NewSelect(ditherOptions, func(_ string) {})
+	maxBitDepthSelector.OnChanged = func(_ string) {
+		// Dither is only applied when reducing to low bit depths
+		bitDepth := bitDepthValues[max(maxBitDepthSelector.SelectedIndex(), 0)]
+		if !maxBitDepthSelector.Disabled() && bitDepth > 0 && bitDepth <= config.MaxDitherBitDepth {
+			ditherSelector.Enable()
+		} else {
+			ditherSelector.SetSelectedIndex(0)
+			ditherSelector.Disable()
+		}
+	}
+	channelPolicySelector := widget.NewSelect([]string{
+		s.Locale.Tr("tab.profiles.form.channels.preserve"),
+		s.Locale.Tr("tab.profiles.form.channels.stereo"),
+		s.Locale.Tr("tab.profiles.form.channels.mono"),
+	}, func(_ string) {})
+	maxArtworkSizeEntry := widget.NewEntry()
+	maxArtworkSizeEntry.SetPlaceHolder(s.Locale.Tr("tab.profiles.form.no-limit"))
+	artworkQualityEntry := widget.NewEntry()
+	artworkQualityEntry.SetPlaceHolder(strconv.Itoa(config.DefaultArtworkQuality))
+	artworkPolicySelector := widget.NewSelect([]string{
+		s.Locale.Tr("tab.profiles.form.artwork.copy"),
+		s.Locale.Tr("tab.profiles.form.artwork.jpeg"),
+		s.Locale.Tr("tab.profiles.form.artwork.strip"),
+	}, func(_ string) {})
+	artworkPolicySelector.OnChanged = func(_ string) {
+		if !artworkPolicySelector.Disabled() && config.ArtworkPolicy(artworkPolicySelector.SelectedIndex()) == config.ArtworkJpeg {
+			maxArtworkSizeEntry.Enable()
+			artworkQualityEntry.Enable()
+		} else {
+			maxArtworkSizeEntry.Disable()
+			artworkQualityEntry.Disable()
+		}
+	}
+	id3VersionValues := []uint{4, 3}
+	id3VersionSelector := widget.NewSelect([]string{"ID3v2.4", "ID3v2.3"}, func(_ string) {})
+	extraArgsEntry := widget.NewEntry()
+	extraArgsEntry.SetPlaceHolder("-application audio -cutoff 20000")
+	audioFilterEntry := widget.NewEntry()
+	audioFilterEntry.SetPlaceHolder("loudnorm")
+	compatibleCodecsEntry := widget.NewEntry()
+	compatibleCodecsEntry.SetPlaceHolder("mp3, aac")
+	formats := s.Config.GetOutputFormats()
+	formatNames := make([]string, 0, len(formats))
+	for _, id := range slices.Sorted(maps.Keys(formats)) {
+		formatNames = append(formatNames, formats[id].Name)
 	}
 	var onFormatSelect func(name string)
 	formatSelector := widget.NewSelect(formatNames, func(name string) {
 		onFormatSelect(name)
 	})
 	onFormatSelect = func(name string) {
-		format := config.GetOutputFormat(name)
+		format := s.Config.GetOutputFormat(name)
+		selectedFormat = format
 		if format.IsLossless {
 			bitrateEntry.Disable()
 			bitrateEntry.SetText("")
+
+			// Formats with a single bit depth, such as 24-bit WAV, always encode at it
+			if len(format.SampleFormats) == 1 {
+				maxBitDepthSelector.SetSelectedIndex(0)
+				maxBitDepthSelector.Disable()
+			} else {
+				maxBitDepthSelector.Enable()
+			}
+			maxBitDepthSelector.OnChanged("")
+
+			// Smart transcoding never converts lossy sources to lossless formats, so they are always copied
+			compatibleCodecsEntry.Disable()
+			compatibleCodecsEntry.SetText("")
 		} else {
 			bitrateEntry.SetText(strconv.Itoa(int(format.SuggestedBitrate)))
 			bitrateEntry.Enable()
+			maxBitDepthSelector.SetSelectedIndex(0)
+			maxBitDepthSelector.Disable()
+			ditherSelector.SetSelectedIndex(0)
+			ditherSelector.Disable()
+			compatibleCodecsEntry.Enable()
 		}
 		isLosslessCheck.SetChecked(format.IsLossless)
 		supportsMetaCheck.SetChecked(format.SupportsMetadata)
 		supportsArtworkCheck.SetChecked(format.SupportsArtwork)
+		if format.SupportsArtwork {
+			artworkPolicySelector.Enable()
+		} else {
+			artworkPolicySelector.Disable()
+		}
+		artworkPolicySelector.OnChanged("")
+		if format.SupportsMetadata && format.TagStyle == config.TagStyleId3 {
+			id3VersionSelector.Enable()
+		} else {
+			id3VersionSelector.SetSelectedIndex(0)
+			id3VersionSelector.Disable()
+		}
+
+		formatModes = format.GetEncodingModes()
+		modeOptions := make([]string, len(formatModes))
+		for i, mode := range formatModes {
+			modeOptions[i] = s.Locale.Tr(encodingModeKeys[mode])
+		}
+		encodingModeSelector.SetOptions(modeOptions)
+		if len(formatModes) > 0 {
+			encodingModeSelector.Enable()
+			encodingModeSelector.SetSelectedIndex(max(slices.Index(formatModes, format.DefaultEncodingMode), 0))
+		} else {
+			encodingModeSelector.ClearSelected()
+			encodingModeSelector.Disable()
+		}
+		onEncodingModeSelect()
 	}
 
 	var onSave func()
@@ -129,15 +268,55 @@ func New(s *logic.AppState, parent fyne.Window) ProfilesTab {
 			format := config.SupportedOutputFormats[0]
 			formatSelector.SetSelected(format.Name)
 			onFormatSelect(format.Name)
+			maxSampleRateEntry.SetText("")
+			maxBitDepthSelector.SetSelectedIndex(0)
+			ditherSelector.SetSelectedIndex(0)
+			channelPolicySelector.SetSelectedIndex(int(config.ChannelPolicyPreserve))
+			id3VersionSelector.SetSelectedIndex(0)
+			artworkPolicySelector.SetSelectedIndex(int(config.ArtworkCopy))
+			maxArtworkSizeEntry.SetText("")
+			artworkQualityEntry.SetText("")
+			extraArgsEntry.SetText("")
+			audioFilterEntry.SetText("")
+			compatibleCodecsEntry.SetText("")
 
 			saveBtn.SetText(s.Locale.Tr("general.create"))
 		} else {
 			nameEntry.SetText(targetProf.Name)
 			formatSelector.SetSelected(targetProf.OutputFormat.Name)
 			onFormatSelect(targetProf.OutputFormat.Name)
-			if !targetProf.OutputFormat.IsLossless {
+			if idx := slices.Index(formatModes, targetProf.EncodingMode); idx >= 0 {
+				encodingModeSelector.SetSelectedIndex(idx)
+				onEncodingModeSelect()
+			}
+			if targetProf.GetEncodingModeOptions().UsesQuality() {
+				qualityEntry.SetText(strconv.FormatFloat(targetProf.Quality, 'f', -1, 64))
+			} else if !targetProf.OutputFormat.IsLossless {
 				bitrateEntry.SetText(strconv.Itoa(int(targetProf.Bitrate)))
 			}
+			if targetProf.MaxSampleRate > 0 {
+				maxSampleRateEntry.SetText(strconv.Itoa(int(targetProf.MaxSampleRate)))
+			} else {
+				maxSampleRateEntry.SetText("")
+			}
+			maxBitDepthSelector.SetSelectedIndex(max(slices.Index(bitDepthValues, targetProf.MaxBitDepth), 0))
+			ditherSelector.SetSelectedIndex(slices.Index(config.DitherMethods, targetProf.DitherMethod) + 1)
+			channelPolicySelector.SetSelectedIndex(int(targetProf.ChannelPolicy))
+			id3VersionSelector.SetSelectedIndex(max(slices.Index(id3VersionValues, targetProf.GetId3Version()), 0))
+			artworkPolicySelector.SetSelectedIndex(int(targetProf.ArtworkPolicy))
+			if targetProf.MaxArtworkSize > 0 {
+				maxArtworkSizeEntry.SetText(strconv.Itoa(int(targetProf.MaxArtworkSize)))
+			} else {
+				maxArtworkSizeEntry.SetText("")
+			}
+			if targetProf.ArtworkQuality > 0 {
+				artworkQualityEntry.SetText(strconv.Itoa(int(targetProf.ArtworkQuality)))
+			} else {
+				artworkQualityEntry.SetText("")
+			}
+			extraArgsEntry.SetText(config.FormatArgs(targetProf.ExtraArgs))
+			audioFilterEntry.SetText(targetProf.AudioFilter)
+			compatibleCodecsEntry.SetText(strings.Join(targetProf.CompatibleCodecs, ", "))
 
 			saveBtn.SetText(s.Locale.Tr("general.save"))
 		}
@@ -150,7 +329,20 @@ func New(s *logic.AppState, parent fyne.Window) ProfilesTab {
 	form.Append("", isLosslessCheck)
 	form.Append("", supportsMetaCheck)
 	form.Append("", supportsArtworkCheck)
+	form.Append(s.Locale.Tr("tab.profiles.form.encoding-mode"), encodingModeSelector)
 	form.Append(s.Locale.Tr("tab.profiles.form.bitrate"), bitrateEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.quality"), qualityEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.max-sample-rate"), maxSampleRateEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.max-bit-depth"), maxBitDepthSelector)
+	form.Append(s.Locale.Tr("tab.profiles.form.dither"), ditherSelector)
+	form.Append(s.Locale.Tr("tab.profiles.form.channels"), channelPolicySelector)
+	form.Append(s.Locale.Tr("tab.profiles.form.id3-version"), id3VersionSelector)
+	form.Append(s.Locale.Tr("tab.profiles.form.artwork"), artworkPolicySelector)
+	form.Append(s.Locale.Tr("tab.profiles.form.max-artwork-size"), maxArtworkSizeEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.artwork-quality"), artworkQualityEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.extra-args"), extraArgsEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.audio-filter"), audioFilterEntry)
+	form.Append(s.Locale.Tr("tab.profiles.form.compatible-codecs"), compatibleCodecsEntry)
 	form.Append("", layout.NewSpacer())
 	form.Append("", saveBtn)
 	form.Append("", errMsg)
@@ -198,24 +390,134 @@ func New(s *logic.AppState, parent fyne.Window) ProfilesTab {
 			return
 		}
 
-		// Formats are hardcoded, so we can assume it exists
-		format := config.GetOutputFormat(formatSelector.Selected)
+		// Only existing formats can be selected, so we can assume it exists
+		format := s.Config.GetOutputFormat(formatSelector.Selected)
+
+		encodingMode := format.DefaultEncodingMode
+		if idx := encodingModeSelector.SelectedIndex(); idx >= 0 {
+			encodingMode = formatModes[idx]
+		}
+		modeOpts := format.EncodingModes[encodingMode]
 
 		bitrate := 0
 		var err error
-		if !format.IsLossless {
+		if !format.IsLossless && !modeOpts.UsesQuality() {
 			bitrate, err = strconv.Atoi(bitrateEntry.Text)
 			if err != nil || bitrate < 1 {
 				errMsg.SetText(s.Locale.Tr("tab.profiles.form.error.invalid-bitrate"))
+				return
+			}
+		}
+
+		var quality float64
+		if modeOpts.UsesQuality() {
+			quality, err = strconv.ParseFloat(qualityEntry.Text, 64)
+			if err != nil || !modeOpts.IsQualityValid(quality) {
+				errMsg.SetText(s.Locale.Tr(
+					"tab.profiles.form.error.invalid-quality",
+					strconv.FormatFloat(modeOpts.MinQuality, 'f', -1, 64),
+					strconv.FormatFloat(modeOpts.MaxQuality, 'f', -1, 64),
+				))
+				return
+			}
+		}
+
+		maxSampleRate := 0
+		if maxSampleRateEntry.Text != "" {
+			maxSampleRate, err = strconv.Atoi(maxSampleRateEntry.Text)
+			if err != nil || maxSampleRate < 1 {
+				errMsg.SetText(s.Locale.Tr("tab.profiles.form.error.invalid-sample-rate"))
+				return
+			}
+		}
+
+		var maxBitDepth uint
+		var ditherMethod string
+		if !maxBitDepthSelector.Disabled() {
+			maxBitDepth = bitDepthValues[max(maxBitDepthSelector.SelectedIndex(), 0)]
+			if idx := ditherSelector.SelectedIndex(); idx > 0 && maxBitDepth > 0 && maxBitDepth <= config.MaxDitherBitDepth {
+				ditherMethod = config.DitherMethods[idx-1]
+			}
+		}
+
+		var id3Version uint
+		if format.SupportsMetadata && format.TagStyle == config.TagStyleId3 {
+			id3Version = id3VersionValues[max(id3VersionSelector.SelectedIndex(), 0)]
+		}
+
+		// Formats without artwork don't use the artwork options, so keep the defaults
+		var artworkPolicy config.ArtworkPolicy
+		maxArtworkSize := 0
+		artworkQuality := 0
+		if format.SupportsArtwork {
+			artworkPolicy = config.ArtworkPolicy(max(artworkPolicySelector.SelectedIndex(), 0))
+		}
+		if artworkPolicy == config.ArtworkJpeg {
+			if maxArtworkSizeEntry.Text != "" {
+				maxArtworkSize, err = strconv.Atoi(maxArtworkSizeEntry.Text)
+				if err != nil || maxArtworkSize < 1 {
+					errMsg.SetText(s.Locale.Tr("tab.profiles.form.error.invalid-artwork-size"))
+					return
+				}
+			}
+			if artworkQualityEntry.Text != "" {
+				artworkQuality, err = strconv.Atoi(artworkQualityEntry.Text)
+				if err != nil || artworkQuality < 1 || artworkQuality > 100 {
+					errMsg.SetText(s.Locale.Tr("tab.profiles.form.error.invalid-artwork-quality"))
+					return
+				}
+			}
+		}
+
+		extraArgs, err := config.ParseArgs(extraArgsEntry.Text)
+		if err == nil {
+			err = config.ValidateExtraArgs(extraArgs)
+		}
+		if err != nil {
+			errMsg.SetText(s.Locale.TrError(err))
+			return
+		}
+
+		audioFilter := strings.TrimSpace(audioFilterEntry.Text)
+		if audioFilter != "" {
+			if err := config.ValidateAudioFilter(audioFilter); err != nil {
+				errMsg.SetText(s.Locale.TrError(err))
+				return
+			}
+		}
+
+		// Codec names are separated by commas or spaces
+		var compatibleCodecs []string
+		if !format.IsLossless {
+			codecs := strings.FieldsFunc(strings.ToLower(compatibleCodecsEntry.Text), func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})
+			for _, codec := range codecs {
+				if !slices.Contains(compatibleCodecs, codec) {
+					compatibleCodecs = append(compatibleCodecs, codec)
+				}
 			}
 		}
 
 		// Config looks good, save it
 		if targetProf == nil {
 			newProf := &config.OutputProfile{
-				Name:         nameEntry.Text,
-				OutputFormat: *format,
-				Bitrate:      uint(bitrate),
+				Name:             nameEntry.Text,
+				OutputFormat:     *format,
+				EncodingMode:     encodingMode,
+				Bitrate:          uint(bitrate),
+				Quality:          quality,
+				ExtraArgs:        extraArgs,
+				AudioFilter:      audioFilter,
+				MaxSampleRate:    uint(maxSampleRate),
+				MaxBitDepth:      maxBitDepth,
+				DitherMethod:     ditherMethod,
+				ChannelPolicy:    config.ChannelPolicy(max(channelPolicySelector.SelectedIndex(), 0)),
+				Id3Version:       id3Version,
+				ArtworkPolicy:    artworkPolicy,
+				MaxArtworkSize:   uint(maxArtworkSize),
+				ArtworkQuality:   uint(artworkQuality),
+				CompatibleCodecs: compatibleCodecs,
 			}
 
 			s.Config.Profiles = append(s.Config.Profiles, newProf)
@@ -223,7 +525,20 @@ func New(s *logic.AppState, parent fyne.Window) ProfilesTab {
 		} else {
 			targetProf.Name = nameEntry.Text
 			targetProf.OutputFormat = *format
+			targetProf.EncodingMode = encodingMode
 			targetProf.Bitrate = uint(bitrate)
+			targetProf.Quality = quality
+			targetProf.ExtraArgs = extraArgs
+			targetProf.AudioFilter = audioFilter
+			targetProf.MaxSampleRate = uint(maxSampleRate)
+			targetProf.MaxBitDepth = maxBitDepth
+			targetProf.DitherMethod = ditherMethod
+			targetProf.ChannelPolicy = config.ChannelPolicy(max(channelPolicySelector.SelectedIndex(), 0))
+			targetProf.Id3Version = id3Version
+			targetProf.ArtworkPolicy = artworkPolicy
+			targetProf.MaxArtworkSize = uint(maxArtworkSize)
+			targetProf.ArtworkQuality = uint(artworkQuality)
+			targetProf.CompatibleCodecs = compatibleCodecs
 		}
 
 		err = s.Save()
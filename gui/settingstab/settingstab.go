@@ -3,28 +3,136 @@ package settingstab
 import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+	"github.com/termermc/your-loss-sync/config"
+	"github.com/termermc/your-loss-sync/logic"
+	"runtime"
+	"strconv"
+	"time"
 )
 
 type SettingsTab struct {
 	Widget fyne.CanvasObject
+
+	setupForm func()
 }
 
 // New creates a new SettingsTab
-func New() SettingsTab {
-	list := widget.NewList(
-		func() int {
-			return 0
-		},
-		func() fyne.CanvasObject {
-			return widget.NewLabel("Sync 1")
-		},
-		func(id widget.ListItemID, o fyne.CanvasObject) {
-
-		})
-	cont := container.NewHBox(list, container.NewVBox())
+func New(s *logic.AppState, parent fyne.Window) SettingsTab {
+	form := widget.NewForm()
+
+	concurrencyEntry := widget.NewEntry()
+	concurrencyEntry.SetPlaceHolder(s.Locale.Tr("tab.settings.default-concurrency-auto", strconv.Itoa(runtime.NumCPU())))
+
+	maxRetriesEntry := widget.NewEntry()
+	backoffEntry := widget.NewEntry()
+
+	retryChecks := make(map[config.RetryErrorClass]*widget.Check, len(config.AllRetryErrorClasses))
+	retryChecksBox := container.NewVBox()
+	for _, class := range config.AllRetryErrorClasses {
+		check := widget.NewCheck(s.Locale.Tr("tab.settings.retry-error."+string(class)), func(_ bool) {})
+		retryChecks[class] = check
+		retryChecksBox.Add(check)
+	}
+
+	var onSave func()
+
+	saveBtn := widget.NewButton(s.Locale.Tr("general.save"), func() {
+		onSave()
+	})
+
+	errMsg := widget.NewLabel("")
+
+	setupForm := func() {
+		errMsg.SetText("")
+
+		if s.Config.DefaultConcurrency > 0 {
+			concurrencyEntry.SetText(strconv.Itoa(int(s.Config.DefaultConcurrency)))
+		} else {
+			concurrencyEntry.SetText("")
+		}
+
+		retryPolicy := s.Config.RetryPolicy
+		maxRetriesEntry.SetText(strconv.Itoa(int(retryPolicy.MaxRetries)))
+		backoffEntry.SetText(strconv.FormatFloat(retryPolicy.Backoff.Seconds(), 'f', -1, 64))
+		for class, check := range retryChecks {
+			check.SetChecked(retryPolicy.IsRetryable(class))
+		}
+	}
+
+	setupForm()
+
+	form.Append(s.Locale.Tr("tab.settings.default-concurrency"), concurrencyEntry)
+	form.Append(s.Locale.Tr("tab.settings.max-retries"), maxRetriesEntry)
+	form.Append(s.Locale.Tr("tab.settings.retry-backoff"), backoffEntry)
+	form.Append(s.Locale.Tr("tab.settings.retryable-errors"), retryChecksBox)
+	form.Append("", layout.NewSpacer())
+	form.Append("", saveBtn)
+	form.Append("", errMsg)
+
+	onSave = func() {
+		errMsg.SetText("")
+
+		concurrency := 0
+		if concurrencyEntry.Text != "" {
+			var err error
+			concurrency, err = strconv.Atoi(concurrencyEntry.Text)
+			if err != nil || concurrency < 1 {
+				errMsg.SetText(s.Locale.Tr("tab.settings.error.invalid-concurrency"))
+				return
+			}
+		}
+
+		maxRetries, err := strconv.Atoi(maxRetriesEntry.Text)
+		if err != nil || maxRetries < 0 {
+			errMsg.SetText(s.Locale.Tr("tab.settings.error.invalid-max-retries"))
+			return
+		}
+
+		backoffSecs, err := strconv.ParseFloat(backoffEntry.Text, 64)
+		if err != nil || backoffSecs < 0 {
+			errMsg.SetText(s.Locale.Tr("tab.settings.error.invalid-retry-backoff"))
+			return
+		}
+
+		retryableErrors := make([]config.RetryErrorClass, 0, len(config.AllRetryErrorClasses))
+		for _, class := range config.AllRetryErrorClasses {
+			if retryChecks[class].Checked {
+				retryableErrors = append(retryableErrors, class)
+			}
+		}
+
+		s.Config.DefaultConcurrency = uint(concurrency)
+		s.Config.RetryPolicy = config.RetryPolicy{
+			MaxRetries:      uint(maxRetries),
+			Backoff:         time.Duration(backoffSecs * float64(time.Second)),
+			RetryableErrors: retryableErrors,
+		}
+
+		err = s.Save()
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+	}
+
+	formScroll := container.NewScroll(
+		container.New(
+			layout.NewFormLayout(),
+			form,
+		),
+	)
+	formScroll.SetMinSize(fyne.NewSize(600, 500))
 
 	return SettingsTab{
-		Widget: cont,
+		Widget:    formScroll,
+		setupForm: setupForm,
 	}
 }
+
+// ResetForm resets the form to its default state.
+func (s *SettingsTab) ResetForm() {
+	s.setupForm()
+}
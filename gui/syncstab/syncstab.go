@@ -10,6 +10,8 @@ import (
 	ylwidget "github.com/termermc/your-loss-sync/gui/widget"
 	"github.com/termermc/your-loss-sync/logic"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type SyncsTab struct {
@@ -57,6 +59,11 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 				return
 			}
 
+			if err := logic.DeleteManifest(s.ConfigDir, sync.Name); err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+
 			list.Widget.UnselectAll()
 			list.Widget.Refresh()
 		}, parent)
@@ -76,6 +83,43 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 	profileSelector := widget.NewSelect([]string{}, func(_ string) {})
 	escapeFilenamesCheck := widget.NewCheck(s.Locale.Tr("tab.syncs.form.escape-filenames"), func(_ bool) {})
 	reencodeSameFormatCheck := widget.NewCheck(s.Locale.Tr("tab.syncs.form.reencode-same-format"), func(_ bool) {})
+	smartTranscodeCheck := widget.NewCheck(s.Locale.Tr("tab.syncs.form.smart-transcode"), func(_ bool) {})
+	mirrorCheck := widget.NewCheck(s.Locale.Tr("tab.syncs.form.mirror"), func(_ bool) {})
+	concurrencyEntry := widget.NewEntry()
+	concurrencyEntry.SetPlaceHolder(s.Locale.Tr("tab.syncs.form.concurrency-default"))
+	lowPriorityCheck := widget.NewCheck(s.Locale.Tr("tab.syncs.form.low-priority"), func(_ bool) {})
+	artworkFilenamesEntry := widget.NewEntry()
+	artworkFilenamesEntry.SetPlaceHolder(strings.Join(config.DefaultArtworkFilenames, ", "))
+	embedArtworkCheck := widget.NewCheck(s.Locale.Tr("tab.syncs.form.embed-folder-artwork"), func(checked bool) {
+		if checked {
+			artworkFilenamesEntry.Enable()
+		} else {
+			artworkFilenamesEntry.Disable()
+		}
+	})
+	sidecarExtsEntry := widget.NewEntry()
+	sidecarExtsEntry.SetPlaceHolder(s.Locale.Tr("tab.syncs.form.sidecar-extensions-hint"))
+	filePolicySelector := widget.NewSelect([]string{
+		s.Locale.Tr("tab.syncs.form.file-policy.copy-all"),
+		s.Locale.Tr("tab.syncs.form.file-policy.audio-only"),
+		s.Locale.Tr("tab.syncs.form.file-policy.audio-and-sidecars"),
+	}, func(_ string) {})
+	filePolicySelector.OnChanged = func(_ string) {
+		if config.FilePolicy(filePolicySelector.SelectedIndex()) == config.FilePolicyAudioAndSidecars {
+			sidecarExtsEntry.Enable()
+		} else {
+			sidecarExtsEntry.Disable()
+		}
+	}
+	conversionRulesEntry := widget.NewMultiLineEntry()
+	conversionRulesEntry.SetPlaceHolder(s.Locale.Tr("tab.syncs.form.conversion-rules-hint"))
+	conversionRulesEntry.SetMinRowsVisible(4)
+	tagRulesEntry := widget.NewMultiLineEntry()
+	tagRulesEntry.SetPlaceHolder(s.Locale.Tr("tab.syncs.form.tag-rules-hint"))
+	tagRulesEntry.SetMinRowsVisible(4)
+	filterRulesEntry := widget.NewMultiLineEntry()
+	filterRulesEntry.SetPlaceHolder(s.Locale.Tr("tab.syncs.form.filter-rules-hint"))
+	filterRulesEntry.SetMinRowsVisible(4)
 
 	var onSave func()
 
@@ -105,6 +149,18 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 			}
 			escapeFilenamesCheck.SetChecked(true)
 			reencodeSameFormatCheck.SetChecked(false)
+			smartTranscodeCheck.SetChecked(false)
+			mirrorCheck.SetChecked(false)
+			concurrencyEntry.SetText("")
+			lowPriorityCheck.SetChecked(false)
+			conversionRulesEntry.SetText("")
+			tagRulesEntry.SetText("")
+			filterRulesEntry.SetText("")
+			filePolicySelector.SetSelectedIndex(int(config.FilePolicyCopyAll))
+			sidecarExtsEntry.SetText(strings.Join(config.DefaultSidecarExtensions, ", "))
+			embedArtworkCheck.SetChecked(false)
+			artworkFilenamesEntry.SetText("")
+			artworkFilenamesEntry.Disable()
 
 			saveBtn.SetText(s.Locale.Tr("general.create"))
 		} else {
@@ -114,6 +170,43 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 			profileSelector.SetSelected(targetSync.Profile.Name)
 			escapeFilenamesCheck.SetChecked(targetSync.EscapeFilenames)
 			reencodeSameFormatCheck.SetChecked(targetSync.ReencodeSameFormat)
+			smartTranscodeCheck.SetChecked(targetSync.SmartTranscode)
+			mirrorCheck.SetChecked(targetSync.Mirror)
+			if targetSync.Concurrency > 0 {
+				concurrencyEntry.SetText(strconv.Itoa(int(targetSync.Concurrency)))
+			} else {
+				concurrencyEntry.SetText("")
+			}
+			lowPriorityCheck.SetChecked(targetSync.LowPriority)
+
+			conversionLines := make([]string, len(targetSync.ConversionRules))
+			for i, rule := range targetSync.ConversionRules {
+				conversionLines[i] = rule.String()
+			}
+			conversionRulesEntry.SetText(strings.Join(conversionLines, "\n"))
+
+			tagLines := make([]string, len(targetSync.TagRules))
+			for i, rule := range targetSync.TagRules {
+				tagLines[i] = rule.String()
+			}
+			tagRulesEntry.SetText(strings.Join(tagLines, "\n"))
+
+			ruleLines := make([]string, len(targetSync.FilterRules))
+			for i, rule := range targetSync.FilterRules {
+				ruleLines[i] = rule.String()
+			}
+			filterRulesEntry.SetText(strings.Join(ruleLines, "\n"))
+
+			filePolicySelector.SetSelectedIndex(int(targetSync.FilePolicy))
+			sidecarExtsEntry.SetText(strings.Join(targetSync.GetSidecarExtensions(), ", "))
+
+			embedArtworkCheck.SetChecked(targetSync.EmbedFolderArtwork)
+			artworkFilenamesEntry.SetText(strings.Join(targetSync.ArtworkFilenames, ", "))
+			if targetSync.EmbedFolderArtwork {
+				artworkFilenamesEntry.Enable()
+			} else {
+				artworkFilenamesEntry.Disable()
+			}
 
 			saveBtn.SetText(s.Locale.Tr("general.save"))
 		}
@@ -125,8 +218,19 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 	form.Append(s.Locale.Tr("tab.syncs.form.source-dir"), srcDirPicker.Widget)
 	form.Append(s.Locale.Tr("tab.syncs.form.dest-dir"), destDirPicker.Widget)
 	form.Append(s.Locale.Tr("tab.syncs.form.profile"), profileSelector)
+	form.Append(s.Locale.Tr("tab.syncs.form.conversion-rules"), conversionRulesEntry)
+	form.Append(s.Locale.Tr("tab.syncs.form.tag-rules"), tagRulesEntry)
 	form.Append("", escapeFilenamesCheck)
 	form.Append("", reencodeSameFormatCheck)
+	form.Append("", smartTranscodeCheck)
+	form.Append("", mirrorCheck)
+	form.Append(s.Locale.Tr("tab.syncs.form.concurrency"), concurrencyEntry)
+	form.Append("", lowPriorityCheck)
+	form.Append(s.Locale.Tr("tab.syncs.form.file-policy"), filePolicySelector)
+	form.Append(s.Locale.Tr("tab.syncs.form.sidecar-extensions"), sidecarExtsEntry)
+	form.Append("", embedArtworkCheck)
+	form.Append(s.Locale.Tr("tab.syncs.form.artwork-filenames"), artworkFilenamesEntry)
+	form.Append(s.Locale.Tr("tab.syncs.form.filter-rules"), filterRulesEntry)
 	form.Append("", layout.NewSpacer())
 	form.Append("", saveBtn)
 	form.Append("", errMsg)
@@ -206,6 +310,80 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 			}
 		}
 
+		concurrency := 0
+		if concurrencyEntry.Text != "" {
+			concurrency, err = strconv.Atoi(concurrencyEntry.Text)
+			if err != nil || concurrency < 1 {
+				errMsg.SetText(s.Locale.Tr("tab.syncs.form.error.invalid-concurrency"))
+				return
+			}
+		}
+
+		var conversionRules []config.ConversionRule
+		for i, line := range strings.Split(conversionRulesEntry.Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			rule, err := config.ParseConversionRule(line, s.Config)
+			if err != nil {
+				errMsg.SetText(s.Locale.Tr("tab.syncs.form.error.invalid-conversion-rule", strconv.Itoa(i+1), s.Locale.TrError(err)))
+				return
+			}
+			conversionRules = append(conversionRules, rule)
+		}
+
+		var tagRules []config.TagRule
+		for i, line := range strings.Split(tagRulesEntry.Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			rule, err := config.ParseTagRule(line)
+			if err != nil {
+				errMsg.SetText(s.Locale.Tr("tab.syncs.form.error.invalid-tag-rule", strconv.Itoa(i+1), s.Locale.TrError(err)))
+				return
+			}
+			tagRules = append(tagRules, rule)
+		}
+
+		var filterRules []config.FilterRule
+		for i, line := range strings.Split(filterRulesEntry.Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			rule, err := config.ParseFilterRule(line)
+			if err != nil {
+				errMsg.SetText(s.Locale.Tr("tab.syncs.form.error.invalid-filter-rule", strconv.Itoa(i+1), s.Locale.TrError(err)))
+				return
+			}
+			filterRules = append(filterRules, rule)
+		}
+
+		// Empty means the default extensions
+		var sidecarExts []string
+		for _, ext := range strings.Split(sidecarExtsEntry.Text, ",") {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if ext != "" {
+				sidecarExts = append(sidecarExts, ext)
+			}
+		}
+
+		// Empty means the default names
+		var artworkFilenames []string
+		for _, name := range strings.Split(artworkFilenamesEntry.Text, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !config.IsArtworkFilenameValid(name) {
+				errMsg.SetText(s.Locale.TrError(config.ErrInvalidArtworkFilename))
+				return
+			}
+			artworkFilenames = append(artworkFilenames, name)
+		}
+
 		// Check if paths exist and are indeed directories
 		srcStat, err := os.Stat(srcDirPath)
 		if err != nil {
@@ -237,26 +415,51 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 			return
 		}
 
-		// Config looks good, save it
+		// Config looks good, save it.
+		// The old name of a renamed sync is kept to move its manifest once the config is saved.
+		var oldName string
 		if targetSync == nil {
 			newSync := &config.SyncConfig{
 				Name:               nameEntry.Text,
 				SourceDir:          srcDirPath,
 				DestDir:            destDirPath,
 				Profile:            s.Config.GetProfile(profileSelector.Selected),
+				ConversionRules:    conversionRules,
+				TagRules:           tagRules,
 				EscapeFilenames:    escapeFilenamesCheck.Checked,
 				ReencodeSameFormat: reencodeSameFormatCheck.Checked,
+				SmartTranscode:     smartTranscodeCheck.Checked,
+				Mirror:             mirrorCheck.Checked,
+				Concurrency:        uint(concurrency),
+				LowPriority:        lowPriorityCheck.Checked,
+				FilterRules:        filterRules,
+				FilePolicy:         config.FilePolicy(filePolicySelector.SelectedIndex()),
+				SidecarExtensions:  sidecarExts,
+				EmbedFolderArtwork: embedArtworkCheck.Checked,
+				ArtworkFilenames:   artworkFilenames,
 			}
 
 			s.Config.Syncs = append(s.Config.Syncs, newSync)
 			targetSync = newSync
 		} else {
+			oldName = targetSync.Name
 			targetSync.Name = nameEntry.Text
 			targetSync.SourceDir = srcDirPath
 			targetSync.DestDir = destDirPath
 			targetSync.Profile = s.Config.GetProfile(profileSelector.Selected)
+			targetSync.ConversionRules = conversionRules
+			targetSync.TagRules = tagRules
 			targetSync.EscapeFilenames = escapeFilenamesCheck.Checked
 			targetSync.ReencodeSameFormat = reencodeSameFormatCheck.Checked
+			targetSync.SmartTranscode = smartTranscodeCheck.Checked
+			targetSync.Mirror = mirrorCheck.Checked
+			targetSync.Concurrency = uint(concurrency)
+			targetSync.LowPriority = lowPriorityCheck.Checked
+			targetSync.FilterRules = filterRules
+			targetSync.FilePolicy = config.FilePolicy(filePolicySelector.SelectedIndex())
+			targetSync.SidecarExtensions = sidecarExts
+			targetSync.EmbedFolderArtwork = embedArtworkCheck.Checked
+			targetSync.ArtworkFilenames = artworkFilenames
 		}
 
 		err = s.Save()
@@ -265,6 +468,15 @@ func New(s *logic.AppState, parent fyne.Window) SyncsTab {
 			return
 		}
 
+		// The manifest is only moved once the new name was saved, so that it always belongs to the saved sync
+		if oldName != "" && oldName != targetSync.Name {
+			err = logic.RenameManifest(s.ConfigDir, oldName, targetSync.Name)
+			if err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+		}
+
 		list.Widget.Refresh()
 		list.Widget.Select(s.Config.GetSyncIndex(nameEntry.Text))
 	}
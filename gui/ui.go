@@ -24,7 +24,7 @@ func (g Gui) initMainWindow() {
 
 	syncsTab := syncstab.New(g.State, w)
 	profilesTab := profilestab.New(g.State, w)
-	settingsTab := settingstab.New()
+	settingsTab := settingstab.New(g.State, w)
 	inProgressTab := inprogresstab.New(g.State, w)
 
 	inProgressTabItem := container.NewTabItem("", inProgressTab.Widget)
@@ -39,6 +39,7 @@ func (g Gui) initMainWindow() {
 	tabs.OnSelected = func(_ *container.TabItem) {
 		syncsTab.ResetForm()
 		profilesTab.ResetForm()
+		settingsTab.ResetForm()
 		inProgressTab.ResetForm()
 	}
 
@@ -99,6 +99,26 @@ var Translations = map[string]map[string]string{
 		"es-419": "ALAC sin pérdidas",
 		"zh-cn":  "无损 ALAC",
 	},
+	"profile.default.vorbis.name": {
+		"en-us":  "High-Quality Ogg Vorbis",
+		"es-419": "Ogg Vorbis de Alta Calidad",
+		"zh-cn":  "高质量 Ogg Vorbis",
+	},
+	"profile.default.wavpack.name": {
+		"en-us":  "Lossless WavPack",
+		"es-419": "WavPack sin pérdidas",
+		"zh-cn":  "无损 WavPack",
+	},
+	"profile.default.wav24.name": {
+		"en-us":  "24-bit WAV",
+		"es-419": "WAV de 24 bits",
+		"zh-cn":  "24 位 WAV",
+	},
+	"profile.default.aiff24.name": {
+		"en-us":  "24-bit AIFF",
+		"es-419": "AIFF de 24 bits",
+		"zh-cn":  "24 位 AIFF",
+	},
 
 	"shell.tab.syncs": {
 		"en-us":  "Syncs",
@@ -131,6 +151,81 @@ var Translations = map[string]map[string]string{
 		"es-419": "Configuración contiene una referencia a un perfil desconocido",
 		"zh-cn":  "配置包含对未知配置文件的引用",
 	},
+	"config.error.unknown-format": {
+		"en-us":  "Config contains a reference to an unknown output format",
+		"es-419": "Configuración contiene una referencia a un formato de salida desconocido",
+		"zh-cn":  "配置包含对未知输出格式的引用",
+	},
+	"config.error.invalid-custom-format": {
+		"en-us":  "Custom output formats must have an ID of at least 1000, a unique name, an extension, an encoder and, if lossy, a suggested bitrate",
+		"es-419": "Los formatos de salida personalizados deben tener un ID de al menos 1000, un nombre único, una extensión, un codificador y, si tienen pérdida, una tasa de bits sugerida",
+		"zh-cn":  "自定义输出格式必须具有至少为 1000 的 ID、唯一的名称、扩展名、编码器，有损格式还必须具有建议比特率",
+	},
+	"config.error.invalid-encoding": {
+		"en-us":  "Config contains a profile with an encoding mode or quality that its format does not support",
+		"es-419": "Configuración contiene un perfil con un modo de codificación o calidad que su formato no soporta",
+		"zh-cn":  "配置包含编码模式或质量不受其格式支持的配置文件",
+	},
+	"config.error.invalid-id3-version": {
+		"en-us":  "Config contains a profile with an unsupported ID3v2 version",
+		"es-419": "Configuración contiene un perfil con una versión de ID3v2 no soportada",
+		"zh-cn":  "配置包含不受支持的 ID3v2 版本的配置文件",
+	},
+	"config.error.invalid-artwork": {
+		"en-us":  "Config contains a profile with an unknown artwork option or a JPEG quality above 100",
+		"es-419": "Configuración contiene un perfil con una opción de portada desconocida o una calidad JPEG mayor que 100",
+		"zh-cn":  "配置包含未知封面选项或 JPEG 质量高于 100 的配置文件",
+	},
+	"config.error.invalid-channel-policy": {
+		"en-us":  "Config contains a profile with an unknown channel option",
+		"es-419": "Configuración contiene un perfil con una opción de canales desconocida",
+		"zh-cn":  "配置包含未知声道选项的配置文件",
+	},
+	"config.error.invalid-file-policy": {
+		"en-us":  "Config contains a sync with an unknown file option",
+		"es-419": "Configuración contiene una sincronización con una opción de archivos desconocida",
+		"zh-cn":  "配置包含未知文件选项的同步",
+	},
+	"config.error.invalid-retry-policy": {
+		"en-us":  "Config contains a retry policy with an unknown error type or a negative delay",
+		"es-419": "Configuración contiene una política de reintentos con un tipo de error desconocido o un retraso negativo",
+		"zh-cn":  "配置包含未知错误类型或负延迟的重试策略",
+	},
+	"config.error.invalid-extra-args": {
+		"en-us":  "Extra FFmpeg arguments must be options followed by a value, and cannot change inputs, outputs, encoders or filters",
+		"es-419": "Los argumentos adicionales de FFmpeg deben ser opciones seguidas de un valor, y no pueden cambiar entradas, salidas, codificadores ni filtros",
+		"zh-cn":  "额外的 FFmpeg 参数必须是后跟值的选项，且不能更改输入、输出、编码器或滤镜",
+	},
+	"config.error.invalid-audio-filter": {
+		"en-us":  "Audio filters must be a single chain of filters separated by commas, without labels or filters that read files",
+		"es-419": "Los filtros de audio deben ser una sola cadena de filtros separados por comas, sin etiquetas ni filtros que lean archivos",
+		"zh-cn":  "音频滤镜必须是以逗号分隔的单条滤镜链，不能包含标签或读取文件的滤镜",
+	},
+	"config.error.invalid-conversion-rule": {
+		"en-us":  "Conversion rules must be conditions such as codec=flac, ext=wav, rate>=88200 or depth<=16, followed by => and copy, skip or profile with a profile name",
+		"es-419": "Las reglas de conversión deben ser condiciones como codec=flac, ext=wav, rate>=88200 o depth<=16, seguidas de => y copy, skip o profile con un nombre de perfil",
+		"zh-cn":  "转换规则必须是 codec=flac、ext=wav、rate>=88200 或 depth<=16 等条件，后跟 => 以及 copy、skip 或 profile 加配置文件名称",
+	},
+	"config.error.invalid-tag-rule": {
+		"en-us":  "Tag rules must be set, remove, copy, replace or fallback followed by a tag name, such as set genre = Rock, remove comment, copy album_artist from artist, replace title \\s+$ => or fallback album_artist = Various Artists",
+		"es-419": "Las reglas de etiquetas deben ser set, remove, copy, replace o fallback seguido de un nombre de etiqueta, como set genre = Rock, remove comment, copy album_artist from artist, replace title \\s+$ => o fallback album_artist = Various Artists",
+		"zh-cn":  "标签规则必须是 set、remove、copy、replace 或 fallback 后跟标签名称，例如 set genre = Rock、remove comment、copy album_artist from artist、replace title \\s+$ => 或 fallback album_artist = Various Artists",
+	},
+	"config.error.invalid-artwork-filename": {
+		"en-us":  "Cover image file names must be JPEG or PNG file names, such as cover.jpg or folder.png",
+		"es-419": "Los nombres de archivo de imágenes de portada deben ser nombres de archivo JPEG o PNG, como cover.jpg o folder.png",
+		"zh-cn":  "封面图片文件名必须是 JPEG 或 PNG 文件名，例如 cover.jpg 或 folder.png",
+	},
+	"config.error.unknown-conversion-profile": {
+		"en-us":  "Conversion rule refers to a profile that does not exist",
+		"es-419": "La regla de conversión hace referencia a un perfil que no existe",
+		"zh-cn":  "转换规则引用了不存在的配置文件",
+	},
+	"config.error.invalid-filter-rule": {
+		"en-us":  "Filter rules must start with \"+ \" to include or \"- \" to exclude, followed by a valid glob pattern",
+		"es-419": "Las reglas de filtro deben comenzar con \"+ \" para incluir o \"- \" para excluir, seguido de un patrón glob válido",
+		"zh-cn":  "过滤规则必须以 \"+ \"（包含）或 \"- \"（排除）开头，后跟有效的通配符模式",
+	},
 
 	"setup.title": {
 		"en-us":  "Setup",
@@ -188,6 +283,121 @@ var Translations = map[string]map[string]string{
 		"es-419": "¿Reencodificar archivos con el mismo formato?",
 		"zh-cn":  "重新编码具有相同格式的文件？",
 	},
+	"tab.syncs.form.smart-transcode": {
+		"en-us":  "Avoid upconverting lossy files?",
+		"es-419": "¿Evitar convertir archivos con pérdida a mayor calidad?",
+		"zh-cn":  "避免提升有损文件的码率？",
+	},
+	"tab.syncs.form.mirror": {
+		"en-us":  "Delete files that no longer exist in the source directory?",
+		"es-419": "¿Eliminar archivos que ya no existen en el directorio de origen?",
+		"zh-cn":  "删除源目录中已不存在的文件？",
+	},
+	"tab.syncs.form.concurrency": {
+		"en-us":  "Files at Once",
+		"es-419": "Archivos a la Vez",
+		"zh-cn":  "同时处理的文件数",
+	},
+	"tab.syncs.form.concurrency-default": {
+		"en-us":  "Default",
+		"es-419": "Predeterminado",
+		"zh-cn":  "默认",
+	},
+	"tab.syncs.form.low-priority": {
+		"en-us":  "Run FFmpeg at low priority?",
+		"es-419": "¿Ejecutar FFmpeg con baja prioridad?",
+		"zh-cn":  "以低优先级运行 FFmpeg？",
+	},
+	"tab.syncs.form.conversion-rules": {
+		"en-us":  "Conversion Rules",
+		"es-419": "Reglas de Conversión",
+		"zh-cn":  "转换规则",
+	},
+	"tab.syncs.form.conversion-rules-hint": {
+		"en-us":  "One rule per line, the first match wins, other files use the profile:\ncodec=flac => profile Opus\next=wav depth>=24 => profile FLAC\ncodec=mp3,aac => copy",
+		"es-419": "Una regla por línea, gana la primera coincidencia, los demás archivos usan el perfil:\ncodec=flac => profile Opus\next=wav depth>=24 => profile FLAC\ncodec=mp3,aac => copy",
+		"zh-cn":  "每行一条规则，以第一条匹配的规则为准，其他文件使用配置文件:\ncodec=flac => profile Opus\next=wav depth>=24 => profile FLAC\ncodec=mp3,aac => copy",
+	},
+	"tab.syncs.form.error.invalid-conversion-rule": {
+		"en-us":  "Invalid conversion rule on line $1: $2",
+		"es-419": "Regla de conversión no válida en la línea $1: $2",
+		"zh-cn":  "第 $1 行的转换规则无效: $2",
+	},
+	"tab.syncs.form.file-policy": {
+		"en-us":  "Files to Sync",
+		"es-419": "Archivos a Sincronizar",
+		"zh-cn":  "要同步的文件",
+	},
+	"tab.syncs.form.file-policy.copy-all": {
+		"en-us":  "All files",
+		"es-419": "Todos los archivos",
+		"zh-cn":  "所有文件",
+	},
+	"tab.syncs.form.file-policy.audio-only": {
+		"en-us":  "Audio files only",
+		"es-419": "Solo archivos de audio",
+		"zh-cn":  "仅音频文件",
+	},
+	"tab.syncs.form.file-policy.audio-and-sidecars": {
+		"en-us":  "Audio files and extra file types",
+		"es-419": "Archivos de audio y tipos de archivo adicionales",
+		"zh-cn":  "音频文件和额外的文件类型",
+	},
+	"tab.syncs.form.sidecar-extensions": {
+		"en-us":  "Extra File Types",
+		"es-419": "Tipos de Archivo Adicionales",
+		"zh-cn":  "额外的文件类型",
+	},
+	"tab.syncs.form.sidecar-extensions-hint": {
+		"en-us":  "Comma-separated extensions, such as jpg, png, lrc",
+		"es-419": "Extensiones separadas por comas, como jpg, png, lrc",
+		"zh-cn":  "以逗号分隔的扩展名，例如 jpg, png, lrc",
+	},
+	"tab.syncs.form.embed-folder-artwork": {
+		"en-us":  "Embed folder images such as cover.jpg into files without artwork",
+		"es-419": "Incrustar imágenes de carpeta como cover.jpg en archivos sin portada",
+		"zh-cn":  "将 cover.jpg 等文件夹图片嵌入到没有封面的文件中",
+	},
+	"tab.syncs.form.artwork-filenames": {
+		"en-us":  "Cover Image Names",
+		"es-419": "Nombres de Imágenes de Portada",
+		"zh-cn":  "封面图片名称",
+	},
+	"tab.syncs.form.filter-rules": {
+		"en-us":  "Filter Rules",
+		"es-419": "Reglas de Filtro",
+		"zh-cn":  "过滤规则",
+	},
+	"tab.syncs.form.filter-rules-hint": {
+		"en-us":  "One rule per line, the first match wins:\n+ **/Live/Keep/**\n- **/Live/**\n- *.log",
+		"es-419": "Una regla por línea, gana la primera coincidencia:\n+ **/Live/Keep/**\n- **/Live/**\n- *.log",
+		"zh-cn":  "每行一条规则，以第一条匹配的规则为准:\n+ **/Live/Keep/**\n- **/Live/**\n- *.log",
+	},
+	"tab.syncs.form.error.invalid-filter-rule": {
+		"en-us":  "Invalid filter rule on line $1: $2",
+		"es-419": "Regla de filtro no válida en la línea $1: $2",
+		"zh-cn":  "第 $1 行的过滤规则无效: $2",
+	},
+	"tab.syncs.form.tag-rules": {
+		"en-us":  "Tag Rules",
+		"es-419": "Reglas de Etiquetas",
+		"zh-cn":  "标签规则",
+	},
+	"tab.syncs.form.tag-rules-hint": {
+		"en-us":  "One rule per line, applied in order:\nfallback album_artist from artist\nremove comment\nreplace title \\s*\\(Remaster(ed)?\\)$ =>\nset genre = Rock",
+		"es-419": "Una regla por línea, aplicadas en orden:\nfallback album_artist from artist\nremove comment\nreplace title \\s*\\(Remaster(ed)?\\)$ =>\nset genre = Rock",
+		"zh-cn":  "每行一条规则，按顺序应用:\nfallback album_artist from artist\nremove comment\nreplace title \\s*\\(Remaster(ed)?\\)$ =>\nset genre = Rock",
+	},
+	"tab.syncs.form.error.invalid-tag-rule": {
+		"en-us":  "Invalid tag rule on line $1: $2",
+		"es-419": "Regla de etiqueta no válida en la línea $1: $2",
+		"zh-cn":  "第 $1 行的标签规则无效: $2",
+	},
+	"tab.syncs.form.error.invalid-concurrency": {
+		"en-us":  "Files at once must be a positive number, or empty to use the default",
+		"es-419": "Los archivos a la vez deben ser un número positivo, o vacío para usar el predeterminado",
+		"zh-cn":  "同时处理的文件数必须为正数，留空则使用默认值",
+	},
 	"tab.syncs.form.error.missing-name": {
 		"en-us":  "Name is required",
 		"es-419": "Se requiere el nombre",
@@ -229,6 +439,62 @@ var Translations = map[string]map[string]string{
 		"zh-cn":  "源和目标目录不能相同",
 	},
 
+	"tab.settings.default-concurrency": {
+		"en-us":  "Default Files at Once",
+		"es-419": "Archivos a la Vez Predeterminados",
+		"zh-cn":  "默认同时处理的文件数",
+	},
+	"tab.settings.default-concurrency-auto": {
+		"en-us":  "Number of CPU threads ($1)",
+		"es-419": "Número de hilos de CPU ($1)",
+		"zh-cn":  "CPU 线程数 ($1)",
+	},
+	"tab.settings.error.invalid-concurrency": {
+		"en-us":  "Default files at once must be a positive number, or empty to use the number of CPU threads",
+		"es-419": "Los archivos a la vez predeterminados deben ser un número positivo, o vacío para usar el número de hilos de CPU",
+		"zh-cn":  "默认同时处理的文件数必须为正数，留空则使用 CPU 线程数",
+	},
+	"tab.settings.max-retries": {
+		"en-us":  "Retries per Failed File",
+		"es-419": "Reintentos por Archivo Fallido",
+		"zh-cn":  "每个失败文件的重试次数",
+	},
+	"tab.settings.retry-backoff": {
+		"en-us":  "First Retry Delay (seconds)",
+		"es-419": "Espera Antes del Primer Reintento (segundos)",
+		"zh-cn":  "首次重试前的等待时间（秒）",
+	},
+	"tab.settings.retryable-errors": {
+		"en-us":  "Retry Errors",
+		"es-419": "Reintentar Errores",
+		"zh-cn":  "重试的错误",
+	},
+	"tab.settings.retry-error.io": {
+		"en-us":  "File system errors",
+		"es-419": "Errores del sistema de archivos",
+		"zh-cn":  "文件系统错误",
+	},
+	"tab.settings.retry-error.ffmpeg-crash": {
+		"en-us":  "FFmpeg crashes",
+		"es-419": "Cierres inesperados de FFmpeg",
+		"zh-cn":  "FFmpeg 崩溃",
+	},
+	"tab.settings.retry-error.ffmpeg-failed": {
+		"en-us":  "FFmpeg errors",
+		"es-419": "Errores de FFmpeg",
+		"zh-cn":  "FFmpeg 错误",
+	},
+	"tab.settings.error.invalid-max-retries": {
+		"en-us":  "Retries per failed file must be 0 or a positive number",
+		"es-419": "Los reintentos por archivo fallido deben ser 0 o un número positivo",
+		"zh-cn":  "每个失败文件的重试次数必须为 0 或正数",
+	},
+	"tab.settings.error.invalid-retry-backoff": {
+		"en-us":  "First retry delay must be 0 or a positive number of seconds",
+		"es-419": "La espera antes del primer reintento debe ser 0 o un número positivo de segundos",
+		"zh-cn":  "首次重试前的等待时间必须为 0 或正数秒",
+	},
+
 	"tab.profiles.create": {
 		"en-us":  "Create Profile",
 		"es-419": "Crear Perfil",
@@ -264,6 +530,166 @@ var Translations = map[string]map[string]string{
 		"es-419": "Bitrate",
 		"zh-cn":  "比特率",
 	},
+	"tab.profiles.form.encoding-mode": {
+		"en-us":  "Encoding Mode",
+		"es-419": "Modo de Codificación",
+		"zh-cn":  "编码模式",
+	},
+	"tab.profiles.form.encoding-mode.cbr": {
+		"en-us":  "Constant bitrate (CBR)",
+		"es-419": "Tasa de bits constante (CBR)",
+		"zh-cn":  "恒定比特率 (CBR)",
+	},
+	"tab.profiles.form.encoding-mode.abr": {
+		"en-us":  "Average bitrate (ABR)",
+		"es-419": "Tasa de bits promedio (ABR)",
+		"zh-cn":  "平均比特率 (ABR)",
+	},
+	"tab.profiles.form.encoding-mode.vbr": {
+		"en-us":  "Variable bitrate (VBR)",
+		"es-419": "Tasa de bits variable (VBR)",
+		"zh-cn":  "可变比特率 (VBR)",
+	},
+	"tab.profiles.form.encoding-mode.compression": {
+		"en-us":  "Compression level",
+		"es-419": "Nivel de compresión",
+		"zh-cn":  "压缩级别",
+	},
+	"tab.profiles.form.quality": {
+		"en-us":  "Quality",
+		"es-419": "Calidad",
+		"zh-cn":  "质量",
+	},
+	"tab.profiles.form.quality.range": {
+		"en-us":  "$1 to $2",
+		"es-419": "$1 a $2",
+		"zh-cn":  "$1 至 $2",
+	},
+	"tab.profiles.form.max-sample-rate": {
+		"en-us":  "Max Sample Rate (Hz)",
+		"es-419": "Frecuencia de Muestreo Máxima (Hz)",
+		"zh-cn":  "最大采样率 (Hz)",
+	},
+	"tab.profiles.form.no-limit": {
+		"en-us":  "No limit",
+		"es-419": "Sin límite",
+		"zh-cn":  "无限制",
+	},
+	"tab.profiles.form.max-bit-depth": {
+		"en-us":  "Max Bit Depth",
+		"es-419": "Profundidad de Bits Máxima",
+		"zh-cn":  "最大位深度",
+	},
+	"tab.profiles.form.bit-depth.source": {
+		"en-us":  "Same as source",
+		"es-419": "Igual que el origen",
+		"zh-cn":  "与源文件相同",
+	},
+	"tab.profiles.form.bit-depth.bits": {
+		"en-us":  "$1-bit",
+		"es-419": "$1 bits",
+		"zh-cn":  "$1 位",
+	},
+	"tab.profiles.form.dither": {
+		"en-us":  "Dither",
+		"es-419": "Tramado",
+		"zh-cn":  "抖动",
+	},
+	"tab.profiles.form.dither.none": {
+		"en-us":  "None",
+		"es-419": "Ninguno",
+		"zh-cn":  "无",
+	},
+	"tab.profiles.form.channels": {
+		"en-us":  "Channels",
+		"es-419": "Canales",
+		"zh-cn":  "声道",
+	},
+	"tab.profiles.form.channels.preserve": {
+		"en-us":  "Same as source",
+		"es-419": "Igual que el origen",
+		"zh-cn":  "与源文件相同",
+	},
+	"tab.profiles.form.channels.stereo": {
+		"en-us":  "Downmix to stereo",
+		"es-419": "Mezclar a estéreo",
+		"zh-cn":  "缩混为立体声",
+	},
+	"tab.profiles.form.channels.mono": {
+		"en-us":  "Downmix to mono",
+		"es-419": "Mezclar a mono",
+		"zh-cn":  "缩混为单声道",
+	},
+	"tab.profiles.form.artwork": {
+		"en-us":  "Artwork",
+		"es-419": "Portada",
+		"zh-cn":  "封面",
+	},
+	"tab.profiles.form.artwork.copy": {
+		"en-us":  "Keep as-is",
+		"es-419": "Mantener sin cambios",
+		"zh-cn":  "保持原样",
+	},
+	"tab.profiles.form.artwork.jpeg": {
+		"en-us":  "Convert to baseline JPEG",
+		"es-419": "Convertir a JPEG básico",
+		"zh-cn":  "转换为基线 JPEG",
+	},
+	"tab.profiles.form.artwork.strip": {
+		"en-us":  "Remove",
+		"es-419": "Eliminar",
+		"zh-cn":  "移除",
+	},
+	"tab.profiles.form.max-artwork-size": {
+		"en-us":  "Max Artwork Size (px)",
+		"es-419": "Tamaño Máximo de Portada (px)",
+		"zh-cn":  "最大封面尺寸 (像素)",
+	},
+	"tab.profiles.form.artwork-quality": {
+		"en-us":  "Artwork JPEG Quality (1-100)",
+		"es-419": "Calidad JPEG de Portada (1-100)",
+		"zh-cn":  "封面 JPEG 质量 (1-100)",
+	},
+	"tab.profiles.form.error.invalid-artwork-size": {
+		"en-us":  "Max artwork size must be a positive number of pixels, or empty for no limit",
+		"es-419": "El tamaño máximo de portada debe ser un número positivo de píxeles, o vacío para no tener límite",
+		"zh-cn":  "最大封面尺寸必须是正的像素数，留空表示不限制",
+	},
+	"tab.profiles.form.error.invalid-artwork-quality": {
+		"en-us":  "Artwork JPEG quality must be a number from 1 to 100, or empty for the default",
+		"es-419": "La calidad JPEG de portada debe ser un número del 1 al 100, o vacío para el predeterminado",
+		"zh-cn":  "封面 JPEG 质量必须是 1 到 100 之间的数字，留空则使用默认值",
+	},
+	"tab.profiles.form.id3-version": {
+		"en-us":  "ID3 version",
+		"es-419": "Versión de ID3",
+		"zh-cn":  "ID3 版本",
+	},
+	"tab.profiles.form.extra-args": {
+		"en-us":  "Extra FFmpeg Arguments",
+		"es-419": "Argumentos Adicionales de FFmpeg",
+		"zh-cn":  "额外的 FFmpeg 参数",
+	},
+	"tab.profiles.form.audio-filter": {
+		"en-us":  "Audio Filters",
+		"es-419": "Filtros de Audio",
+		"zh-cn":  "音频滤镜",
+	},
+	"tab.profiles.form.compatible-codecs": {
+		"en-us":  "Compatible Lossy Codecs",
+		"es-419": "Códecs con Pérdida Compatibles",
+		"zh-cn":  "兼容的有损编解码器",
+	},
+	"tab.profiles.form.error.invalid-sample-rate": {
+		"en-us":  "Max sample rate must be a positive number, or empty for no limit",
+		"es-419": "La frecuencia de muestreo máxima debe ser un número positivo, o vacía para no tener límite",
+		"zh-cn":  "最大采样率必须为正数，留空则无限制",
+	},
+	"tab.profiles.form.error.invalid-quality": {
+		"en-us":  "Quality must be a number from $1 to $2",
+		"es-419": "La calidad debe ser un número de $1 a $2",
+		"zh-cn":  "质量必须是 $1 至 $2 之间的数字",
+	},
 	"tab.profiles.form.error.invalid-bitrate": {
 		"en-us":  "Invalid bitrate",
 		"es-419": "Bitrate inválido",
@@ -305,6 +731,66 @@ var Translations = map[string]map[string]string{
 		"es-419": "Cancelar",
 		"zh-cn":  "取消",
 	},
+	"tab.progress.pause": {
+		"en-us":  "Pause",
+		"es-419": "Pausar",
+		"zh-cn":  "暂停",
+	},
+	"tab.progress.resume": {
+		"en-us":  "Resume",
+		"es-419": "Reanudar",
+		"zh-cn":  "继续",
+	},
+	"tab.progress.preview": {
+		"en-us":  "Preview",
+		"es-419": "Vista previa",
+		"zh-cn":  "预览",
+	},
+	"tab.progress.retry-failed": {
+		"en-us":  "Retry failed",
+		"es-419": "Reintentar fallidos",
+		"zh-cn":  "重试失败的文件",
+	},
+	"tab.progress.export-failures": {
+		"en-us":  "Export failures",
+		"es-419": "Exportar fallos",
+		"zh-cn":  "导出失败记录",
+	},
+	"tab.progress.preview-summary": {
+		"en-us":  "Transcode: $1, copy: $2, skip: $3, delete: $4, failed: $5",
+		"es-419": "Transcodificar: $1, copiar: $2, omitir: $3, eliminar: $4, fallidos: $5",
+		"zh-cn":  "转码: $1, 复制: $2, 跳过: $3, 删除: $4, 失败: $5",
+	},
+	"tab.progress.status-label-paused": {
+		"en-us":  "Paused. Completed: $1/$2, failed: $3",
+		"es-419": "En pausa. Completado: $1/$2, fallidos: $3",
+		"zh-cn":  "已暂停。已完成: $1/$2, 失败: $3",
+	},
+	"tab.progress.status-label-cancelled": {
+		"en-us":  "Cancelled. Completed: $1/$2, failed: $3",
+		"es-419": "Cancelado. Completado: $1/$2, fallidos: $3",
+		"zh-cn":  "已取消。已完成: $1/$2, 失败: $3",
+	},
+	"tab.progress.stale-confirm.title": {
+		"en-us":  "Delete Stale Files",
+		"es-419": "Eliminar Archivos Obsoletos",
+		"zh-cn":  "删除过时文件",
+	},
+	"tab.progress.stale-confirm.description": {
+		"en-us":  "$1 files in the destination directory no longer exist in the source directory:\n\n$2\n\nDelete them before syncing?",
+		"es-419": "$1 archivos del directorio de destino ya no existen en el directorio de origen:\n\n$2\n\n¿Eliminarlos antes de sincronizar?",
+		"zh-cn":  "目标目录中有 $1 个文件已不存在于源目录中：\n\n$2\n\n是否在同步前删除它们？",
+	},
+	"tab.progress.log-dropped": {
+		"en-us":  "($1 log lines were dropped because the log could not keep up)",
+		"es-419": "(se descartaron $1 líneas del registro porque el registro no pudo mantener el ritmo)",
+		"zh-cn":  "（日志跟不上，已丢弃 $1 行日志）",
+	},
+	"tab.progress.stale-confirm.more": {
+		"en-us":  "...and $1 more",
+		"es-419": "...y $1 más",
+		"zh-cn":  "...以及另外 $1 个",
+	},
 	"tab.progress.status-label": {
 		"en-us":  "Completed: $1/$2, failed: $3",
 		"es-419": "Completado: $1/$2, fallidos: $3",
@@ -326,14 +812,164 @@ var Translations = map[string]map[string]string{
 		"es-419": "Transcodificando $1",
 		"zh-cn":  "正在转码 $1",
 	},
-	"sync.path-already-exists": {
-		"en-us":  "Path $1 already exists, skipping",
-		"es-419": "La ruta $1 ya existe, se omite",
-		"zh-cn":  "路径 $1 已存在，将跳过",
+	"sync.deleting": {
+		"en-us":  "Deleting $1",
+		"es-419": "Eliminando $1",
+		"zh-cn":  "正在删除 $1",
+	},
+	"sync.removing-outdated": {
+		"en-us":  "Removing outdated $1",
+		"es-419": "Eliminando $1 desactualizado",
+		"zh-cn":  "正在删除过时的 $1",
+	},
+	"sync.channels": {
+		"en-us":  "channels: $1",
+		"es-419": "canales: $1",
+		"zh-cn":  "声道: $1",
+	},
+	"sync.channels.downmix": {
+		"en-us":  "channels: $1 → $2",
+		"es-419": "canales: $1 → $2",
+		"zh-cn":  "声道: $1 → $2",
+	},
+	"sync.dropped-tags": {
+		"en-us":  "dropped tags: $1",
+		"es-419": "etiquetas descartadas: $1",
+		"zh-cn":  "丢弃的标签: $1",
+	},
+	"sync.tag-changes": {
+		"en-us":  "tags: $1",
+		"es-419": "etiquetas: $1",
+		"zh-cn":  "标签: $1",
+	},
+	"sync.tag-change.set": {
+		"en-us":  "$1 = \"$2\"",
+		"es-419": "$1 = \"$2\"",
+		"zh-cn":  "$1 = \"$2\"",
+	},
+	"sync.tag-change.removed": {
+		"en-us":  "$1 removed",
+		"es-419": "$1 eliminada",
+		"zh-cn":  "已删除 $1",
+	},
+	"sync.artwork": {
+		"en-us":  "artwork: $1",
+		"es-419": "portada: $1",
+		"zh-cn":  "封面: $1",
+	},
+	"sync.event.queued": {
+		"en-us":  "Queued $1",
+		"es-419": "$1 en cola",
+		"zh-cn":  "已将 $1 加入队列",
+	},
+	"sync.event.transcoded": {
+		"en-us":  "Transcoded $1 to $2",
+		"es-419": "Se transcodificó $1 a $2",
+		"zh-cn":  "已将 $1 转码为 $2",
+	},
+	"sync.event.copied": {
+		"en-us":  "Copied $1 to $2",
+		"es-419": "Se copió $1 a $2",
+		"zh-cn":  "已将 $1 复制到 $2",
+	},
+	"sync.event.skipped": {
+		"en-us":  "Skipped $1 ($2)",
+		"es-419": "Se omitió $1 ($2)",
+		"zh-cn":  "已跳过 $1 ($2)",
+	},
+	"sync.event.retrying": {
+		"en-us":  "Retrying $1 (retry $2) after error: $3",
+		"es-419": "Reintentando $1 (reintento $2) tras un error: $3",
+		"zh-cn":  "出错后重试 $1（第 $2 次重试）: $3",
+	},
+	"sync.event.failed": {
+		"en-us":  "Failed to sync $1: $2",
+		"es-419": "No se pudo sincronizar $1: $2",
+		"zh-cn":  "同步 $1 失败: $2",
+	},
+	"sync.plan.transcode": {
+		"en-us":  "Transcode $1 to $2 with $3 ($4)",
+		"es-419": "Transcodificar $1 a $2 con $3 ($4)",
+		"zh-cn":  "使用 $3 将 $1 转码为 $2 ($4)",
+	},
+	"sync.plan.transcode-overwrite": {
+		"en-us":  "Transcode $1 to $2 with $3, overwriting it ($4)",
+		"es-419": "Transcodificar $1 a $2 con $3, sobrescribiéndolo ($4)",
+		"zh-cn":  "使用 $3 将 $1 转码为 $2 并覆盖 ($4)",
+	},
+	"sync.plan.copy": {
+		"en-us":  "Copy $1 to $2 ($3)",
+		"es-419": "Copiar $1 a $2 ($3)",
+		"zh-cn":  "将 $1 复制到 $2 ($3)",
+	},
+	"sync.plan.copy-overwrite": {
+		"en-us":  "Copy $1 to $2, overwriting it ($3)",
+		"es-419": "Copiar $1 a $2, sobrescribiéndolo ($3)",
+		"zh-cn":  "将 $1 复制到 $2 并覆盖 ($3)",
+	},
+	"sync.plan.skip": {
+		"en-us":  "Skip $1 ($2)",
+		"es-419": "Omitir $1 ($2)",
+		"zh-cn":  "跳过 $1 ($2)",
+	},
+	"sync.plan.delete": {
+		"en-us":  "Delete $1 ($2)",
+		"es-419": "Eliminar $1 ($2)",
+		"zh-cn":  "删除 $1 ($2)",
+	},
+	"sync.plan.fail": {
+		"en-us":  "Cannot sync $1 ($2): $3",
+		"es-419": "No se puede sincronizar $1 ($2): $3",
+		"zh-cn":  "无法同步 $1 ($2): $3",
+	},
+	"sync.reason.new": {
+		"en-us":  "new file",
+		"es-419": "archivo nuevo",
+		"zh-cn":  "新文件",
+	},
+	"sync.reason.source-changed": {
+		"en-us":  "source file changed",
+		"es-419": "el archivo de origen cambió",
+		"zh-cn":  "源文件已更改",
+	},
+	"sync.reason.profile-changed": {
+		"en-us":  "profile changed",
+		"es-419": "el perfil cambió",
+		"zh-cn":  "配置文件已更改",
+	},
+	"sync.reason.output-missing": {
+		"en-us":  "output file is missing",
+		"es-419": "falta el archivo de salida",
+		"zh-cn":  "输出文件缺失",
+	},
+	"sync.reason.up-to-date": {
+		"en-us":  "up-to-date",
+		"es-419": "actualizado",
+		"zh-cn":  "已是最新",
+	},
+	"sync.reason.already-exists": {
+		"en-us":  "output already exists",
+		"es-419": "la salida ya existe",
+		"zh-cn":  "输出已存在",
+	},
+	"sync.reason.stale": {
+		"en-us":  "no longer in source directory",
+		"es-419": "ya no está en el directorio de origen",
+		"zh-cn":  "已不在源目录中",
+	},
+	"sync.reason.probe-failed": {
+		"en-us":  "could not be probed",
+		"es-419": "no se pudo analizar",
+		"zh-cn":  "无法探测",
 	},
 	"sync.done": {
 		"en-us":  "Done (total: $1, completed: $2, failed: $3)",
 		"es-419": "Hecho (total: $1, completados: $2, fallidos: $3)",
 		"zh-cn":  "完成 (总计: $1, 已完成: $2, 失败: $3)",
 	},
+	"sync.cancelled": {
+		"en-us":  "Cancelled (total: $1, completed: $2, failed: $3)",
+		"es-419": "Cancelado (total: $1, completados: $2, fallidos: $3)",
+		"zh-cn":  "已取消 (总计: $1, 已完成: $2, 失败: $3)",
+	},
 }
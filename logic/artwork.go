@@ -0,0 +1,70 @@
+package logic
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// findFolderArtwork returns the full path to the most preferred folder image in a source directory, or an empty string
+// if there is none.
+// Results are cached, since every audio file in the directory looks for the same image.
+func (p *Plan) findFolderArtwork(dir string) string {
+	p.folderArtworkLock.Lock()
+	defer p.folderArtworkLock.Unlock()
+
+	if artworkPath, has := p.folderArtwork[dir]; has {
+		return artworkPath
+	}
+
+	// A directory that can't be read simply has no folder image
+	entries, _ := os.ReadDir(dir)
+
+	bestIdx := -1
+	var bestName string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		idx := slices.IndexFunc(p.Sync.GetArtworkFilenames(), func(name string) bool {
+			return strings.EqualFold(name, entry.Name())
+		})
+		if idx >= 0 && (bestIdx < 0 || idx < bestIdx) {
+			bestIdx = idx
+			bestName = entry.Name()
+		}
+	}
+
+	var res string
+	if bestIdx >= 0 {
+		res = filepath.Join(dir, bestName)
+	}
+
+	if p.folderArtwork == nil {
+		p.folderArtwork = make(map[string]string)
+	}
+	p.folderArtwork[dir] = res
+
+	return res
+}
+
+// artworkArgs returns the FFmpeg arguments that embed the folder image of an action into its output as the front cover.
+// They must come after the source file's input, and map the source file's streams with mapSource.
+// If the action has no folder image, nil is returned.
+func artworkArgs(action *Action, mapSource string) []string {
+	if action.ArtworkPath == "" {
+		return nil
+	}
+
+	// The comment sets the picture type of ID3 and FLAC pictures
+	return []string{
+		"-i", action.ArtworkPath,
+		"-map", mapSource,
+		"-map", "1:v:0",
+		"-disposition:v:0", "attached_pic",
+		"-metadata:s:v:0", "title=Album cover",
+		"-metadata:s:v:0", "comment=Cover (front)",
+	}
+}
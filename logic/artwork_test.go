@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/termermc/your-loss-sync/config"
+)
+
+func TestFindFolderArtwork(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"song.flac", "Folder.JPG", "front.png", "back.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Directories are never artwork, even if their names match
+	if err := os.Mkdir(filepath.Join(dir, "cover.jpg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		filenames []string
+		want      string
+	}{
+		{"default names in order of preference, ignoring case", nil, "Folder.JPG"},
+		{"custom names", []string{"back.jpg", "front.png"}, "back.jpg"},
+		{"no matching image", []string{"cover.png"}, ""},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{Sync: &config.SyncConfig{ArtworkFilenames: test.filenames}}
+
+		want := ""
+		if test.want != "" {
+			want = filepath.Join(dir, test.want)
+		}
+		if got := plan.findFolderArtwork(dir); got != want {
+			t.Errorf("%s: findFolderArtwork() = %q, want %q", test.name, got, want)
+		}
+	}
+
+	// Results are cached per directory
+	plan := &Plan{Sync: &config.SyncConfig{}}
+	first := plan.findFolderArtwork(dir)
+	if err := os.Remove(first); err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.findFolderArtwork(dir); got != first {
+		t.Errorf("findFolderArtwork() after the image was removed = %q, want the cached %q", got, first)
+	}
+
+	if got := plan.findFolderArtwork(filepath.Join(dir, "missing")); got != "" {
+		t.Errorf("findFolderArtwork() of a missing directory = %q, want none", got)
+	}
+}
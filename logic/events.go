@@ -0,0 +1,219 @@
+package logic
+
+import (
+	"github.com/termermc/your-loss-sync/config"
+	"github.com/termermc/your-loss-sync/lang"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventType is the type of a sync event.
+type EventType int
+
+const (
+	// EventScanStarted is emitted when a sync starts scanning its source directory.
+	EventScanStarted EventType = iota
+
+	// EventFileQueued is emitted when a planned action is queued for execution.
+	EventFileQueued
+
+	// EventTranscodeStarted is emitted when a file starts being transcoded.
+	EventTranscodeStarted
+
+	// EventTranscodeFinished is emitted when a file was successfully transcoded.
+	EventTranscodeFinished
+
+	// EventCopyStarted is emitted when a file starts being copied.
+	EventCopyStarted
+
+	// EventCopyFinished is emitted when a file was successfully copied.
+	EventCopyFinished
+
+	// EventFileSkipped is emitted when a file is skipped.
+	// The reason is the action's reason.
+	EventFileSkipped
+
+	// EventFileFailed is emitted when a file failed to sync.
+	EventFileFailed
+
+	// EventFileRetrying is emitted when a file that failed is about to be retried.
+	EventFileRetrying
+
+	// EventFileDeleted is emitted when a stale file was deleted from the destination directory.
+	EventFileDeleted
+
+	// EventOutdatedRemoved is emitted when the previous output of a file was removed after it was replaced.
+	EventOutdatedRemoved
+
+	// EventError is emitted when an error occurs that does not cause a file to fail.
+	EventError
+
+	// EventSyncFinished is emitted when a sync finished, whether it completed or was cancelled.
+	EventSyncFinished
+)
+
+// SyncTotals are the final counts of a sync.
+type SyncTotals struct {
+	// The final status of the sync.
+	Status SyncStatus
+
+	// The total number of files.
+	Total int64
+
+	// The number of files that were synced or skipped.
+	Completed int64
+
+	// The number of files that failed.
+	Failed int64
+}
+
+// Event is an event emitted during a sync.
+type Event struct {
+	// The type of event.
+	Type EventType
+
+	// When the event occurred.
+	Time time.Time
+
+	// The sync the event belongs to.
+	Sync *config.SyncConfig
+
+	// The action the event relates to.
+	// Nil for events that don't relate to a single file.
+	Action *Action
+
+	// The error that occurred.
+	// Only set for EventFileFailed, EventFileRetrying and EventError.
+	Err error
+
+	// The number of the upcoming retry, starting at 1.
+	// Only set for EventFileRetrying.
+	Retry uint
+
+	// The sync's final counts.
+	// Only set for EventSyncFinished.
+	Totals SyncTotals
+}
+
+// Describe returns a human-readable description of the event.
+func (e Event) Describe(locale lang.Locale) string {
+	var srcRelative, destRelative string
+	if e.Action != nil {
+		srcRelative = e.Action.SourceRelative
+		destRelative = e.Action.DestRelative
+	}
+
+	switch e.Type {
+	case EventScanStarted:
+		return locale.Tr("sync.scanning-source")
+	case EventFileQueued:
+		return locale.Tr("sync.event.queued", srcRelative)
+	case EventTranscodeStarted:
+		return locale.Tr("sync.transcoding", srcRelative) + e.Action.describeDetails(locale)
+	case EventTranscodeFinished:
+		return locale.Tr("sync.event.transcoded", srcRelative, destRelative)
+	case EventCopyStarted:
+		return locale.Tr("sync.copying", srcRelative) + e.Action.describeTagChanges(locale)
+	case EventCopyFinished:
+		return locale.Tr("sync.event.copied", srcRelative, destRelative)
+	case EventFileSkipped:
+		return locale.Tr("sync.event.skipped", srcRelative, locale.Tr(string(e.Action.Reason)))
+	case EventFileFailed:
+		if srcRelative == "" {
+			srcRelative = destRelative
+		}
+		return locale.Tr("sync.event.failed", srcRelative, locale.TrError(e.Err))
+	case EventFileRetrying:
+		return locale.Tr("sync.event.retrying", srcRelative, strconv.Itoa(int(e.Retry)), locale.TrError(e.Err))
+	case EventFileDeleted:
+		return locale.Tr("sync.deleting", destRelative)
+	case EventOutdatedRemoved:
+		return locale.Tr("sync.removing-outdated", e.Action.ReplacesRelative)
+	case EventError:
+		return locale.Tr("general.error") + ": " + locale.TrError(e.Err)
+	default:
+		key := "sync.done"
+		if e.Totals.Status == SyncStatusCancelled {
+			key = "sync.cancelled"
+		}
+		return locale.Tr(
+			key,
+			strconv.FormatInt(e.Totals.Total, 10),
+			strconv.FormatInt(e.Totals.Completed, 10),
+			strconv.FormatInt(e.Totals.Failed, 10),
+		)
+	}
+}
+
+// EventBus distributes sync events to subscribers.
+// The zero value is an empty bus.
+// It is safe for concurrent use.
+type EventBus struct {
+	lock     sync.Mutex
+	handlers []eventHandler
+	nextId   int
+}
+
+// eventHandler is a handler subscribed to an EventBus.
+type eventHandler struct {
+	id      int
+	handler func(Event)
+}
+
+// Subscribe registers a handler that is called for every event.
+// Handlers are called synchronously from the goroutine that emitted the event, possibly concurrently, so they should
+// return quickly and be safe for concurrent use.
+// For each event, handlers are called in the order they were subscribed.
+// Returns a function that unsubscribes the handler.
+func (b *EventBus) Subscribe(handler func(Event)) (unsubscribe func()) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	id := b.nextId
+	b.nextId++
+	b.handlers = append(b.handlers, eventHandler{id: id, handler: handler})
+
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		// A new slice is created so that events being emitted keep using the handlers they copied
+		b.handlers = slices.DeleteFunc(slices.Clone(b.handlers), func(h eventHandler) bool {
+			return h.id == id
+		})
+	}
+}
+
+// Emit sends an event to all subscribers.
+// If the event's time is not set, it is set to the current time.
+func (b *EventBus) Emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	// Subscribing only appends and unsubscribing replaces the slice, so the handlers can be used without the lock
+	b.lock.Lock()
+	handlers := b.handlers
+	b.lock.Unlock()
+
+	for _, h := range handlers {
+		h.handler(event)
+	}
+}
+
+// NewLogWriter returns an event handler that writes a timestamped line describing each event to the specified writer.
+func NewLogWriter(writer io.Writer, locale lang.Locale) func(Event) {
+	lock := sync.Mutex{}
+
+	return func(event Event) {
+		line := event.Time.Format(time.DateTime) + " " + event.Describe(locale) + "\n"
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		_, _ = io.WriteString(writer, line)
+	}
+}
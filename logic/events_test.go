@@ -0,0 +1,105 @@
+package logic
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEventBusOrder(t *testing.T) {
+	var bus EventBus
+	var calls []string
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		bus.Subscribe(func(event Event) {
+			calls = append(calls, name+":"+strconv.Itoa(int(event.Retry)))
+		})
+	}
+
+	bus.Emit(Event{Retry: 1})
+	bus.Emit(Event{Retry: 2})
+
+	want := []string{"a:1", "b:1", "c:1", "d:1", "e:1", "a:2", "b:2", "c:2", "d:2", "e:2"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("handlers were called as %q, want %q", calls, want)
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	var bus EventBus
+	var calls []string
+
+	unsubA := bus.Subscribe(func(event Event) {
+		calls = append(calls, "a")
+	})
+	unsubB := bus.Subscribe(func(event Event) {
+		calls = append(calls, "b")
+	})
+	bus.Subscribe(func(event Event) {
+		calls = append(calls, "c")
+	})
+
+	unsubB()
+	bus.Emit(Event{})
+	if want := []string{"a", "c"}; !slices.Equal(calls, want) {
+		t.Errorf("after unsubscribing b, handlers were called as %q, want %q", calls, want)
+	}
+
+	// Unsubscribing twice does nothing, and new handlers still come last
+	calls = nil
+	unsubB()
+	unsubA()
+	bus.Subscribe(func(event Event) {
+		calls = append(calls, "d")
+	})
+	bus.Emit(Event{})
+	if want := []string{"c", "d"}; !slices.Equal(calls, want) {
+		t.Errorf("after unsubscribing a, handlers were called as %q, want %q", calls, want)
+	}
+}
+
+func TestEventBusUnsubscribeDuringEmit(t *testing.T) {
+	var bus EventBus
+	var calls []string
+
+	var unsubB func()
+	bus.Subscribe(func(event Event) {
+		calls = append(calls, "a")
+		unsubB()
+	})
+	unsubB = bus.Subscribe(func(event Event) {
+		calls = append(calls, "b")
+	})
+
+	// The event being emitted still reaches b, but the next one doesn't
+	bus.Emit(Event{})
+	bus.Emit(Event{})
+	if want := []string{"a", "b", "a"}; !slices.Equal(calls, want) {
+		t.Errorf("handlers were called as %q, want %q", calls, want)
+	}
+}
+
+func TestEventBusTime(t *testing.T) {
+	var bus EventBus
+	var times []time.Time
+	bus.Subscribe(func(event Event) {
+		times = append(times, event.Time)
+	})
+
+	before := time.Now()
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	bus.Emit(Event{})
+	bus.Emit(Event{Time: fixed})
+
+	if times[0].Before(before) || times[0].After(time.Now()) {
+		t.Errorf("emitted event without a time got %v, want the current time", times[0])
+	}
+	if !times[1].Equal(fixed) {
+		t.Errorf("emitted event with a time got %v, want %v", times[1], fixed)
+	}
+
+	// Emitting without subscribers does nothing
+	var empty EventBus
+	empty.Emit(Event{})
+}
@@ -10,6 +10,27 @@ import (
 	"sync/atomic"
 )
 
+// SyncStatus is the status of a sync.
+type SyncStatus int32
+
+const (
+	// SyncStatusIdle means that no sync has been run yet.
+	SyncStatusIdle SyncStatus = iota
+
+	// SyncStatusRunning means that a sync is running.
+	SyncStatusRunning
+
+	// SyncStatusCompleted means that the last sync ran to completion.
+	// Some files may still have failed.
+	SyncStatusCompleted
+
+	// SyncStatusCancelled means that the last sync was cancelled before it completed.
+	SyncStatusCancelled
+
+	// SyncStatusPaused means that a sync is running, but paused.
+	SyncStatusPaused
+)
+
 // AppState is the state of the application.
 type AppState struct {
 	Config     *config.Config
@@ -18,9 +39,45 @@ type AppState struct {
 	Locale     lang.Locale
 	Progress   struct {
 		Sync      atomic.Pointer[config.SyncConfig]
+		Status    atomic.Int32 // A SyncStatus
 		Completed atomic.Int64
 		Failed    atomic.Int64
 		Total     atomic.Int64
+
+		// The failure report of the current or last sync.
+		// Nil if the last sync failed before any files were synced.
+		Report atomic.Pointer[FailureReport]
+	}
+
+	// Events emitted by syncs.
+	Events EventBus
+
+	pause pauseController
+}
+
+// SyncStatus returns the status of the current or last sync.
+func (s *AppState) SyncStatus() SyncStatus {
+	return SyncStatus(s.Progress.Status.Load())
+}
+
+// PauseSync pauses the running sync.
+// Workers stop starting new files, and running FFmpeg processes are suspended where supported.
+// Does nothing if no sync is running.
+func (s *AppState) PauseSync() {
+	if s.SyncStatus() != SyncStatusRunning {
+		return
+	}
+
+	if s.pause.Pause() {
+		s.Progress.Status.Store(int32(SyncStatusPaused))
+	}
+}
+
+// ResumeSync resumes a sync paused by PauseSync.
+// Does nothing if the sync is not paused.
+func (s *AppState) ResumeSync() {
+	if s.pause.Resume() {
+		s.Progress.Status.CompareAndSwap(int32(SyncStatusPaused), int32(SyncStatusRunning))
 	}
 }
 
@@ -0,0 +1,162 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/termermc/your-loss-sync/util"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ManifestDirName is the name of the directory inside the config directory where sync manifests are stored.
+const ManifestDirName = "manifests"
+
+// ManifestVersion is the current manifest JSON version.
+const ManifestVersion = 1
+
+// ManifestEntry is a record of a source file that was synced.
+type ManifestEntry struct {
+	// The size of the source file in bytes when it was synced.
+	Size int64 `json:"size"`
+
+	// The modification time of the source file when it was synced.
+	ModTime time.Time `json:"modTime"`
+
+	// The path of the output file, relative to the sync's destination directory.
+	OutputPath string `json:"outputPath"`
+
+	// The fingerprint of the output settings that were in use when the file was synced.
+	// See outputFingerprint.
+	ProfileFingerprint string `json:"profileFingerprint"`
+
+	// Whether the output is a copy of the source file that was remuxed to rewrite its tags or embed artwork.
+	IsRemuxed bool `json:"isRemuxed"`
+}
+
+// Manifest is a record of the files synced by a sync.
+// It is used to determine which source files changed since the last sync.
+// It is safe for concurrent use.
+type Manifest struct {
+	// The manifest JSON version.
+	Version int `json:"version"`
+
+	// All entries, keyed by source file path relative to the sync's source directory.
+	Entries map[string]ManifestEntry `json:"entries"`
+
+	lock sync.Mutex
+}
+
+// NewManifest creates a new empty manifest.
+func NewManifest() *Manifest {
+	return &Manifest{
+		Version: ManifestVersion,
+		Entries: make(map[string]ManifestEntry),
+	}
+}
+
+// GetManifestPath returns the path to the manifest file of the sync with the specified name.
+func GetManifestPath(cfgDir string, syncName string) string {
+	return filepath.Join(cfgDir, ManifestDirName, util.EscapeFilename(syncName)+".json")
+}
+
+// LoadManifest loads a manifest from the specified path.
+// If the file does not exist, an empty manifest is returned.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return NewManifest(), nil
+		}
+
+		return nil, err
+	}
+
+	res := NewManifest()
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Entries == nil {
+		res.Entries = make(map[string]ManifestEntry)
+	}
+
+	return res, nil
+}
+
+// Save saves the manifest to the specified path, creating its parent directory if necessary.
+func (m *Manifest) Save(path string) error {
+	m.lock.Lock()
+	data, err := json.Marshal(m)
+	m.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a crash doesn't leave a corrupt manifest behind
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// Get returns the entry for the specified source file, and whether it exists.
+func (m *Manifest) Get(srcRelative string) (ManifestEntry, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	entry, has := m.Entries[srcRelative]
+	return entry, has
+}
+
+// Set sets the entry for the specified source file.
+func (m *Manifest) Set(srcRelative string, entry ManifestEntry) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Entries[srcRelative] = entry
+}
+
+// Retain removes all entries whose source files are not in the specified set.
+func (m *Manifest) Retain(srcRelatives map[string]struct{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for srcRelative := range m.Entries {
+		if _, has := srcRelatives[srcRelative]; !has {
+			delete(m.Entries, srcRelative)
+		}
+	}
+}
+
+// RenameManifest renames the manifest of a sync after the sync itself was renamed.
+// Does nothing if the sync has no manifest.
+func RenameManifest(cfgDir string, oldName string, newName string) error {
+	err := os.Rename(GetManifestPath(cfgDir, oldName), GetManifestPath(cfgDir, newName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteManifest deletes the manifest of a sync.
+// Does nothing if the sync has no manifest.
+func DeleteManifest(cfgDir string, syncName string) error {
+	err := os.Remove(GetManifestPath(cfgDir, syncName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
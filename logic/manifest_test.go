@@ -0,0 +1,186 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/termermc/your-loss-sync/config"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ManifestDirName, "sync.json")
+
+	manifest := NewManifest()
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	manifest.Set("Album/01.flac", ManifestEntry{
+		Size:               1234,
+		ModTime:            modTime,
+		OutputPath:         "Album/01.mp3",
+		ProfileFingerprint: "0:mp3:libmp3lame:320000",
+	})
+	manifest.Set("Album/02.mp3", ManifestEntry{
+		Size:       99,
+		ModTime:    modTime,
+		OutputPath: "Album/02.mp3",
+		IsRemuxed:  true,
+	})
+
+	if err := manifest.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary manifest was left behind: %v", err)
+	}
+
+	loaded, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if loaded.Version != ManifestVersion || len(loaded.Entries) != 2 {
+		t.Fatalf("LoadManifest() = version %d with %d entries", loaded.Version, len(loaded.Entries))
+	}
+	for srcRelative, want := range manifest.Entries {
+		got, has := loaded.Get(srcRelative)
+		if !has || got.Size != want.Size || !got.ModTime.Equal(want.ModTime) || got.OutputPath != want.OutputPath ||
+			got.ProfileFingerprint != want.ProfileFingerprint || got.IsRemuxed != want.IsRemuxed {
+			t.Errorf("entry %q = %+v, want %+v", srcRelative, got, want)
+		}
+	}
+
+	loaded.Retain(map[string]struct{}{"Album/02.mp3": {}})
+	if _, has := loaded.Get("Album/01.flac"); has {
+		t.Error("Retain() kept an entry that is not in the set")
+	}
+	if _, has := loaded.Get("Album/02.mp3"); !has {
+		t.Error("Retain() removed an entry that is in the set")
+	}
+}
+
+func TestLoadManifestMissingOrBroken(t *testing.T) {
+	dir := t.TempDir()
+
+	manifest, err := LoadManifest(filepath.Join(dir, "missing.json"))
+	if err != nil || manifest == nil || len(manifest.Entries) != 0 {
+		t.Errorf("LoadManifest() of a missing file = %v, %v, want an empty manifest", manifest, err)
+	}
+
+	brokenPath := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(brokenPath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(brokenPath); err == nil {
+		t.Error("LoadManifest() of a broken file returned no error")
+	}
+
+	// A manifest without entries can still be added to
+	emptyPath := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(emptyPath, []byte(`{"version": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err = LoadManifest(emptyPath)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	manifest.Set("a.txt", ManifestEntry{})
+}
+
+func TestRenameAndDeleteManifest(t *testing.T) {
+	cfgDir := t.TempDir()
+
+	// Syncs without a manifest can be renamed and deleted
+	if err := RenameManifest(cfgDir, "none", "other"); err != nil {
+		t.Errorf("RenameManifest() without a manifest error = %v", err)
+	}
+	if err := DeleteManifest(cfgDir, "none"); err != nil {
+		t.Errorf("DeleteManifest() without a manifest error = %v", err)
+	}
+
+	if err := NewManifest().Save(GetManifestPath(cfgDir, "old/name")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameManifest(cfgDir, "old/name", "new"); err != nil {
+		t.Fatalf("RenameManifest() error = %v", err)
+	}
+	if _, err := os.Stat(GetManifestPath(cfgDir, "old/name")); !os.IsNotExist(err) {
+		t.Errorf("old manifest still exists: %v", err)
+	}
+	if _, err := os.Stat(GetManifestPath(cfgDir, "new")); err != nil {
+		t.Errorf("renamed manifest is missing: %v", err)
+	}
+
+	if err := DeleteManifest(cfgDir, "new"); err != nil {
+		t.Fatalf("DeleteManifest() error = %v", err)
+	}
+	if _, err := os.Stat(GetManifestPath(cfgDir, "new")); !os.IsNotExist(err) {
+		t.Errorf("deleted manifest still exists: %v", err)
+	}
+}
+
+// planReasons plans a sync and returns the type and reason of the action of each source file.
+func planReasons(t *testing.T, s *AppState, sync *config.SyncConfig) map[string]string {
+	t.Helper()
+
+	plan, err := PlanSync(context.Background(), s, sync)
+	if err != nil {
+		t.Fatalf("PlanSync() error = %v", err)
+	}
+
+	res := make(map[string]string)
+	for _, action := range plan.Actions {
+		res[action.SourceRelative] = fmt.Sprintf("%d:%s", action.Type, action.Reason)
+	}
+
+	return res
+}
+
+func TestManifestChangeDetection(t *testing.T) {
+	s := newTestState(t)
+	srcDir := t.TempDir()
+	writeFiles(t, srcDir, "a.txt", "b.txt", "c.txt")
+
+	sync := &config.SyncConfig{
+		Name:      "changes",
+		SourceDir: srcDir,
+		DestDir:   t.TempDir(),
+		Profile:   config.DefaultOutputProfiles[0],
+	}
+
+	want := fmt.Sprintf("%d:%s", ActionCopy, ReasonNew)
+	for path, got := range planReasons(t, s, sync) {
+		if got != want {
+			t.Errorf("first plan of %q = %s, want %s", path, got, want)
+		}
+	}
+
+	if status := StartSync(context.Background(), s, sync, nil); status != SyncStatusCompleted {
+		t.Fatalf("StartSync() = %d", status)
+	}
+
+	// Modify one source file, and remove the output of another
+	later := time.Now().Add(time.Hour)
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(srcDir, "a.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(sync.DestDir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := planReasons(t, s, sync)
+	wantReasons := map[string]string{
+		"a.txt": fmt.Sprintf("%d:%s", ActionCopy, ReasonSourceChanged),
+		"b.txt": fmt.Sprintf("%d:%s", ActionCopy, ReasonOutputMissing),
+		"c.txt": fmt.Sprintf("%d:%s", ActionSkip, ReasonUpToDate),
+	}
+	for path, want := range wantReasons {
+		if got[path] != want {
+			t.Errorf("plan of %q after changes = %s, want %s", path, got[path], want)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package logic
+
+import (
+	"github.com/termermc/your-loss-sync/config"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tag is a single metadata tag.
+type tag struct {
+	// The tag's name.
+	// Known tags use their canonical name, see knownTags.
+	Key string
+
+	// The tag's value.
+	Value string
+}
+
+// knownTag is a tag that is named differently depending on the container.
+type knownTag struct {
+	// The canonical name of the tag, which is FFmpeg's generic name for it if there is one.
+	Name string
+
+	// The normalized names that the tag is stored under in source files, see normalizeTagKey.
+	Aliases []string
+
+	// The names of the tag in each tag style.
+	// If a style has no name, its containers can't hold the tag.
+	// TagStyleOgg uses the names of TagStyleVorbis, and TagStyleFfmpeg uses the canonical name.
+	Names map[config.TagStyle]string
+}
+
+// knownTags are the tags that are mapped between containers, in the order they are written.
+// FFmpeg converts its generic names to ID3 frames, MP4 atoms and RIFF INFO chunks itself.
+// MusicBrainz IDs follow the names used by MusicBrainz Picard.
+var knownTags = []knownTag{
+	{Name: "title", Aliases: []string{"title"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "TITLE", config.TagStyleApe: "Title", config.TagStyleId3: "title", config.TagStyleMp4: "title", config.TagStyleRiffInfo: "title",
+	}},
+	{Name: "artist", Aliases: []string{"artist"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "ARTIST", config.TagStyleApe: "Artist", config.TagStyleId3: "artist", config.TagStyleMp4: "artist", config.TagStyleRiffInfo: "artist",
+	}},
+	{Name: "album", Aliases: []string{"album"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "ALBUM", config.TagStyleApe: "Album", config.TagStyleId3: "album", config.TagStyleMp4: "album", config.TagStyleRiffInfo: "album",
+	}},
+	{Name: "album_artist", Aliases: []string{"albumartist"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "ALBUMARTIST", config.TagStyleApe: "Album Artist", config.TagStyleId3: "album_artist", config.TagStyleMp4: "album_artist",
+	}},
+	{Name: "date", Aliases: []string{"date", "year"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "DATE", config.TagStyleApe: "Year", config.TagStyleId3: "date", config.TagStyleMp4: "date", config.TagStyleRiffInfo: "date",
+	}},
+	{Name: "genre", Aliases: []string{"genre"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "GENRE", config.TagStyleApe: "Genre", config.TagStyleId3: "genre", config.TagStyleMp4: "genre", config.TagStyleRiffInfo: "genre",
+	}},
+	{Name: "comment", Aliases: []string{"comment"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "COMMENT", config.TagStyleApe: "Comment", config.TagStyleId3: "comment", config.TagStyleMp4: "comment", config.TagStyleRiffInfo: "comment",
+	}},
+	{Name: "composer", Aliases: []string{"composer"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "COMPOSER", config.TagStyleApe: "Composer", config.TagStyleId3: "composer", config.TagStyleMp4: "composer",
+	}},
+	{Name: "track", Aliases: []string{"track", "tracknumber"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "TRACKNUMBER", config.TagStyleApe: "Track", config.TagStyleId3: "track", config.TagStyleMp4: "track", config.TagStyleRiffInfo: "track",
+	}},
+	{Name: "disc", Aliases: []string{"disc", "discnumber"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "DISCNUMBER", config.TagStyleApe: "Disc", config.TagStyleId3: "disc", config.TagStyleMp4: "disc",
+	}},
+	{Name: "compilation", Aliases: []string{"compilation", "cpil"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "COMPILATION", config.TagStyleApe: "Compilation", config.TagStyleId3: "compilation", config.TagStyleMp4: "compilation",
+	}},
+	{Name: "copyright", Aliases: []string{"copyright"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "COPYRIGHT", config.TagStyleApe: "Copyright", config.TagStyleId3: "copyright", config.TagStyleMp4: "copyright", config.TagStyleRiffInfo: "copyright",
+	}},
+	{Name: "lyrics", Aliases: []string{"lyrics", "unsyncedlyrics"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "LYRICS", config.TagStyleApe: "Lyrics", config.TagStyleId3: "lyrics", config.TagStyleMp4: "lyrics",
+	}},
+	// ID3 stores recording IDs in UFID frames, which FFmpeg can't write
+	{Name: "musicbrainz_trackid", Aliases: []string{"musicbrainztrackid"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "MUSICBRAINZ_TRACKID", config.TagStyleApe: "MUSICBRAINZ_TRACKID",
+	}},
+	{Name: "musicbrainz_releasetrackid", Aliases: []string{"musicbrainzreleasetrackid"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "MUSICBRAINZ_RELEASETRACKID", config.TagStyleApe: "MUSICBRAINZ_RELEASETRACKID", config.TagStyleId3: "MusicBrainz Release Track Id",
+	}},
+	{Name: "musicbrainz_albumid", Aliases: []string{"musicbrainzalbumid"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "MUSICBRAINZ_ALBUMID", config.TagStyleApe: "MUSICBRAINZ_ALBUMID", config.TagStyleId3: "MusicBrainz Album Id",
+	}},
+	{Name: "musicbrainz_artistid", Aliases: []string{"musicbrainzartistid"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "MUSICBRAINZ_ARTISTID", config.TagStyleApe: "MUSICBRAINZ_ARTISTID", config.TagStyleId3: "MusicBrainz Artist Id",
+	}},
+	{Name: "musicbrainz_albumartistid", Aliases: []string{"musicbrainzalbumartistid"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "MUSICBRAINZ_ALBUMARTISTID", config.TagStyleApe: "MUSICBRAINZ_ALBUMARTISTID", config.TagStyleId3: "MusicBrainz Album Artist Id",
+	}},
+	{Name: "musicbrainz_releasegroupid", Aliases: []string{"musicbrainzreleasegroupid"}, Names: map[config.TagStyle]string{
+		config.TagStyleVorbis: "MUSICBRAINZ_RELEASEGROUPID", config.TagStyleApe: "MUSICBRAINZ_RELEASEGROUPID", config.TagStyleId3: "MusicBrainz Release Group Id",
+	}},
+}
+
+// ignoredTagKeys are the normalized names of tags that describe the source file rather than its content.
+// They are neither carried over nor reported as dropped.
+var ignoredTagKeys = []string{
+	"encoder",
+	"majorbrand",
+	"minorversion",
+	"compatiblebrands",
+	"creationtime",
+	"handlername",
+	"vendorid",
+	"language",
+	"itunsmpb",
+	"itunnorm",
+}
+
+// normalizeTagKey returns a tag name in lowercase and without anything but letters and digits, so that the names
+// different containers use for the same tag can be compared.
+func normalizeTagKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, key)
+}
+
+// getTags returns the tags of the probed file.
+// Known tags are renamed to their canonical names and come first, in the order of knownTags, followed by the
+// remaining tags sorted by name.
+// Tags are read from both the container and the audio stream, since some containers such as Ogg store them in the
+// stream.
+// If both have the same tag, the container's is used.
+func (r ffprobeResult) getTags() []tag {
+	var rawTags []tag
+	addTags := func(tags map[string]string) {
+		keys := make([]string, 0, len(tags))
+		for key := range tags {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			rawTags = append(rawTags, tag{Key: key, Value: tags[key]})
+		}
+	}
+	addTags(r.Format.Tags)
+	for _, stream := range r.Streams {
+		if stream.CodecType == "audio" {
+			addTags(stream.Tags)
+			break
+		}
+	}
+
+	var res []tag
+	seen := make(map[string]struct{})
+	for _, rawTag := range rawTags {
+		if slices.Contains(ignoredTagKeys, normalizeTagKey(rawTag.Key)) {
+			continue
+		}
+
+		id := tagId(rawTag.Key)
+		if _, has := seen[id]; has {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		res = append(res, tag{Key: canonicalTagName(rawTag.Key), Value: rawTag.Value})
+	}
+	sortTags(res)
+
+	return res
+}
+
+// canonicalTagName returns the canonical name of the known tag with the specified name, or the name as-is if it is not
+// a known tag.
+func canonicalTagName(key string) string {
+	normKey := normalizeTagKey(key)
+	for _, knownTag := range knownTags {
+		if slices.Contains(knownTag.Aliases, normKey) {
+			return knownTag.Name
+		}
+	}
+
+	return key
+}
+
+// tagId returns a string that is the same for all names of a tag.
+func tagId(key string) string {
+	name := canonicalTagName(key)
+	if getKnownTag(name) != nil {
+		return name
+	}
+
+	return normalizeTagKey(name)
+}
+
+// sortTags sorts tags so that known tags come first, in the order of knownTags, followed by the remaining tags sorted
+// by name.
+func sortTags(tags []tag) {
+	slices.SortStableFunc(tags, func(a, b tag) int {
+		aIdx, bIdx := knownTagIndex(a.Key), knownTagIndex(b.Key)
+		switch {
+		case aIdx >= 0 && bIdx >= 0:
+			return aIdx - bIdx
+		case aIdx >= 0:
+			return -1
+		case bIdx >= 0:
+			return 1
+		default:
+			return strings.Compare(a.Key, b.Key)
+		}
+	})
+}
+
+// getKnownTag returns the known tag with the specified canonical name, or nil if there is none.
+func getKnownTag(name string) *knownTag {
+	if idx := knownTagIndex(name); idx >= 0 {
+		return &knownTags[idx]
+	}
+
+	return nil
+}
+
+// knownTagIndex returns the index of the known tag with the specified canonical name in knownTags, or -1 if there is
+// none.
+func knownTagIndex(name string) int {
+	return slices.IndexFunc(knownTags, func(t knownTag) bool {
+		return t.Name == name
+	})
+}
+
+// formatTags converts tags to the names used by the specified output format.
+// Returns the converted tags, and the names of the tags that the format can't hold.
+func formatTags(format config.OutputFormat, tags []tag) ([]tag, []string) {
+	if !format.SupportsMetadata {
+		dropped := make([]string, len(tags))
+		for i, t := range tags {
+			dropped[i] = t.Key
+		}
+		return nil, dropped
+	}
+
+	style := format.TagStyle
+	if style == config.TagStyleOgg {
+		style = config.TagStyleVorbis
+	}
+
+	// MP4 atoms and RIFF INFO chunks only cover known tags, while other containers can store arbitrary ones
+	isFreeForm := style != config.TagStyleMp4 && style != config.TagStyleRiffInfo
+
+	var res []tag
+	var dropped []string
+	for _, t := range tags {
+		key := t.Key
+		if knownTag := getKnownTag(t.Key); knownTag != nil && style != config.TagStyleFfmpeg {
+			key = knownTag.Names[style]
+		} else if !isFreeForm {
+			key = ""
+		} else if style == config.TagStyleVorbis {
+			key = strings.ToUpper(key)
+		}
+
+		if key == "" {
+			dropped = append(dropped, t.Key)
+			continue
+		}
+
+		res = append(res, tag{Key: key, Value: t.Value})
+	}
+
+	return res, dropped
+}
+
+// metadataArgs returns the FFmpeg arguments that write the tags of an action to an output in the specified format.
+// Tags that were not rewritten by tag rules are left to FFmpeg for formats with TagStyleFfmpeg.
+func metadataArgs(action *Action, format config.OutputFormat, id3Version uint) []string {
+	// Custom formats don't say how they store tags, so FFmpeg maps them itself
+	if format.SupportsMetadata && format.TagStyle == config.TagStyleFfmpeg && len(action.TagChanges) == 0 {
+		return nil
+	}
+
+	// Tags are written explicitly, so FFmpeg's own mapping is disabled.
+	// Artwork streams keep their metadata though, since it holds the picture type.
+	args := []string{"-map_metadata", "-1"}
+	if action.hasArtwork {
+		args = append(args, "-map_metadata:s:v", "0:s:v")
+	}
+
+	if !format.SupportsMetadata {
+		return args
+	}
+
+	tags, _ := formatTags(format, action.tags)
+	metadataOpt := "-metadata"
+	if format.TagStyle == config.TagStyleOgg {
+		metadataOpt = "-metadata:s:a:0"
+	}
+	for _, t := range tags {
+		args = append(args, metadataOpt, t.Key+"="+t.Value)
+	}
+
+	if format.TagStyle == config.TagStyleId3 {
+		args = append(args, "-id3v2_version", strconv.Itoa(int(id3Version)))
+	}
+
+	return append(args, format.TagArgs...)
+}
+
+// TagChange is a change that tag rules make to a tag.
+type TagChange struct {
+	// The canonical name of the tag.
+	Tag string
+
+	// The tag's value before the change.
+	// Empty if the tag was added.
+	OldValue string
+
+	// The tag's value after the change.
+	// Empty if the tag was removed.
+	NewValue string
+}
+
+// applyTagRules applies tag rules to tags, in order.
+// Returns the rewritten tags, and the changes that were made.
+func applyTagRules(rules []config.TagRule, tags []tag) ([]tag, []TagChange) {
+	res := slices.Clone(tags)
+
+	indexOf := func(key string) int {
+		id := tagId(key)
+		return slices.IndexFunc(res, func(t tag) bool {
+			return tagId(t.Key) == id
+		})
+	}
+	getValue := func(key string) string {
+		if idx := indexOf(key); idx >= 0 {
+			return res[idx].Value
+		}
+		return ""
+	}
+	setValue := func(key string, value string) {
+		idx := indexOf(key)
+		switch {
+		case idx >= 0 && value == "":
+			res = slices.Delete(res, idx, idx+1)
+		case idx >= 0:
+			res[idx].Value = value
+		case value != "":
+			res = append(res, tag{Key: canonicalTagName(key), Value: value})
+		}
+	}
+
+	for _, rule := range rules {
+		switch rule.Action {
+		case config.TagSet:
+			setValue(rule.Tag, rule.Value)
+		case config.TagRemove:
+			setValue(rule.Tag, "")
+		case config.TagCopy:
+			if value := getValue(rule.Source); value != "" {
+				setValue(rule.Tag, value)
+			}
+		case config.TagReplace:
+			if value := getValue(rule.Tag); value != "" {
+				setValue(rule.Tag, rule.Regexp().ReplaceAllString(value, rule.Value))
+			}
+		case config.TagFallback:
+			if getValue(rule.Tag) != "" {
+				continue
+			}
+			if rule.Source != "" {
+				setValue(rule.Tag, getValue(rule.Source))
+			} else {
+				setValue(rule.Tag, rule.Value)
+			}
+		}
+	}
+	sortTags(res)
+
+	var changes []TagChange
+	for _, t := range res {
+		oldIdx := slices.IndexFunc(tags, func(oldTag tag) bool {
+			return tagId(oldTag.Key) == tagId(t.Key)
+		})
+		if oldIdx < 0 {
+			changes = append(changes, TagChange{Tag: t.Key, NewValue: t.Value})
+		} else if tags[oldIdx].Value != t.Value {
+			changes = append(changes, TagChange{Tag: t.Key, OldValue: tags[oldIdx].Value, NewValue: t.Value})
+		}
+	}
+	for _, t := range tags {
+		if !slices.ContainsFunc(res, func(newTag tag) bool {
+			return tagId(newTag.Key) == tagId(t.Key)
+		}) {
+			changes = append(changes, TagChange{Tag: t.Key, OldValue: t.Value})
+		}
+	}
+
+	return res, changes
+}
+
+// getTagFormat returns the built-in output format that a source file with the specified audio stream is already in, for
+// rewriting the tags of raw copies.
+// FFmpeg can't write every container it can read, so if no format matches, false is returned.
+func getTagFormat(audio sourceAudio) (config.OutputFormat, bool) {
+	for _, id := range slices.Sorted(maps.Keys(config.SupportedOutputFormats)) {
+		if format := config.SupportedOutputFormats[id]; format.Accepts(audio.Codec, audio.Containers) {
+			return format, true
+		}
+	}
+
+	return config.OutputFormat{}, false
+}
@@ -0,0 +1,297 @@
+package logic
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/termermc/your-loss-sync/config"
+)
+
+// mustParseTagRules parses tag rules, failing the test if any of them is invalid.
+func mustParseTagRules(t *testing.T, strs ...string) []config.TagRule {
+	t.Helper()
+
+	rules := make([]config.TagRule, len(strs))
+	for i, str := range strs {
+		rule, err := config.ParseTagRule(str)
+		if err != nil {
+			t.Fatalf("ParseTagRule(%q) error = %v", str, err)
+		}
+		rules[i] = rule
+	}
+
+	return rules
+}
+
+func TestApplyTagRules(t *testing.T) {
+	source := []tag{
+		{Key: "title", Value: "Song (Remastered)"},
+		{Key: "artist", Value: "Doe, Jane"},
+		{Key: "comment", Value: "Ripped by me"},
+		{Key: "MOOD", Value: "Calm"},
+	}
+
+	tests := []struct {
+		name        string
+		rules       []string
+		wantTags    []tag
+		wantChanges []TagChange
+	}{
+		{
+			name:  "set adds and overwrites",
+			rules: []string{"set genre = Jazz", "set title = Other"},
+			wantTags: []tag{
+				{Key: "title", Value: "Other"},
+				{Key: "artist", Value: "Doe, Jane"},
+				{Key: "genre", Value: "Jazz"},
+				{Key: "comment", Value: "Ripped by me"},
+				{Key: "MOOD", Value: "Calm"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "title", OldValue: "Song (Remastered)", NewValue: "Other"},
+				{Tag: "genre", NewValue: "Jazz"},
+			},
+		},
+		{
+			name:  "remove ignores case and separators",
+			rules: []string{"remove Comment", "remove mood", "remove missing"},
+			wantTags: []tag{
+				{Key: "title", Value: "Song (Remastered)"},
+				{Key: "artist", Value: "Doe, Jane"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "comment", OldValue: "Ripped by me"},
+				{Tag: "MOOD", OldValue: "Calm"},
+			},
+		},
+		{
+			name:  "copy uses the canonical name of known tags",
+			rules: []string{"copy ALBUMARTIST from artist", "copy genre from missing"},
+			wantTags: []tag{
+				{Key: "title", Value: "Song (Remastered)"},
+				{Key: "artist", Value: "Doe, Jane"},
+				{Key: "album_artist", Value: "Doe, Jane"},
+				{Key: "comment", Value: "Ripped by me"},
+				{Key: "MOOD", Value: "Calm"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "album_artist", NewValue: "Doe, Jane"},
+			},
+		},
+		{
+			name:  "replace with groups",
+			rules: []string{"replace artist ^(.+), (.+)$ => $2 $1", `replace title \s*\(Remastered\)$ =>`},
+			wantTags: []tag{
+				{Key: "title", Value: "Song"},
+				{Key: "artist", Value: "Jane Doe"},
+				{Key: "comment", Value: "Ripped by me"},
+				{Key: "MOOD", Value: "Calm"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "title", OldValue: "Song (Remastered)", NewValue: "Song"},
+				{Tag: "artist", OldValue: "Doe, Jane", NewValue: "Jane Doe"},
+			},
+		},
+		{
+			name:  "replace yielding an empty value removes the tag",
+			rules: []string{"replace comment ^Ripped by .*$ =>", "replace missing .* => x"},
+			wantTags: []tag{
+				{Key: "title", Value: "Song (Remastered)"},
+				{Key: "artist", Value: "Doe, Jane"},
+				{Key: "MOOD", Value: "Calm"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "comment", OldValue: "Ripped by me"},
+			},
+		},
+		{
+			name:  "fallback only fills missing tags",
+			rules: []string{"fallback album_artist from artist", "fallback genre = Unknown", "fallback title = Untitled", "fallback album from missing"},
+			wantTags: []tag{
+				{Key: "title", Value: "Song (Remastered)"},
+				{Key: "artist", Value: "Doe, Jane"},
+				{Key: "album_artist", Value: "Doe, Jane"},
+				{Key: "genre", Value: "Unknown"},
+				{Key: "comment", Value: "Ripped by me"},
+				{Key: "MOOD", Value: "Calm"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "album_artist", NewValue: "Doe, Jane"},
+				{Tag: "genre", NewValue: "Unknown"},
+			},
+		},
+		{
+			name:  "rules apply in order",
+			rules: []string{"remove title", "fallback title from artist", "set ALBUM_ARTIST = Various", "remove albumartist"},
+			wantTags: []tag{
+				{Key: "title", Value: "Doe, Jane"},
+				{Key: "artist", Value: "Doe, Jane"},
+				{Key: "comment", Value: "Ripped by me"},
+				{Key: "MOOD", Value: "Calm"},
+			},
+			wantChanges: []TagChange{
+				{Tag: "title", OldValue: "Song (Remastered)", NewValue: "Doe, Jane"},
+			},
+		},
+		{
+			name:        "no rules change nothing",
+			rules:       nil,
+			wantTags:    source,
+			wantChanges: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := slices.Clone(source)
+			gotTags, gotChanges := applyTagRules(mustParseTagRules(t, test.rules...), input)
+
+			if !slices.Equal(gotTags, test.wantTags) {
+				t.Errorf("tags = %+v, want %+v", gotTags, test.wantTags)
+			}
+			if !slices.Equal(gotChanges, test.wantChanges) {
+				t.Errorf("changes = %+v, want %+v", gotChanges, test.wantChanges)
+			}
+			if !slices.Equal(input, source) {
+				t.Errorf("source tags were modified: %+v", input)
+			}
+		})
+	}
+}
+
+func TestTagId(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"ALBUMARTIST", "album_artist", true},
+		{"Album Artist", "albumartist", true},
+		{"YEAR", "date", true},
+		{"MOOD", "mood", true},
+		{"REPLAYGAIN_TRACK_GAIN", "replaygain track gain", true},
+		{"artist", "album_artist", false},
+	}
+
+	for _, test := range tests {
+		if same := tagId(test.a) == tagId(test.b); same != test.same {
+			t.Errorf("tagId(%q) == tagId(%q) is %v, want %v", test.a, test.b, same, test.same)
+		}
+	}
+}
+
+func TestFormatTags(t *testing.T) {
+	// Tags are given by their canonical names
+	source := []tag{
+		{Key: "album_artist", Value: "Various Artists"},
+		{Key: "title", Value: "Song"},
+		{Key: "mood", Value: "Calm"},
+	}
+
+	tests := []struct {
+		format      config.OutputFormat
+		wantTags    []tag
+		wantDropped []string
+	}{
+		{
+			config.SupportedOutputFormats[0],
+			[]tag{{Key: "album_artist", Value: "Various Artists"}, {Key: "title", Value: "Song"}, {Key: "mood", Value: "Calm"}},
+			nil,
+		},
+		{
+			config.SupportedOutputFormats[1],
+			[]tag{{Key: "ALBUMARTIST", Value: "Various Artists"}, {Key: "TITLE", Value: "Song"}, {Key: "MOOD", Value: "Calm"}},
+			nil,
+		},
+		{
+			config.SupportedOutputFormats[8],
+			[]tag{{Key: "Album Artist", Value: "Various Artists"}, {Key: "Title", Value: "Song"}, {Key: "mood", Value: "Calm"}},
+			nil,
+		},
+		{
+			config.SupportedOutputFormats[4],
+			[]tag{{Key: "album_artist", Value: "Various Artists"}, {Key: "title", Value: "Song"}},
+			[]string{"mood"},
+		},
+		{
+			config.SupportedOutputFormats[2],
+			[]tag{{Key: "title", Value: "Song"}},
+			[]string{"album_artist", "mood"},
+		},
+		{
+			config.OutputFormat{Name: "No Tags"},
+			nil,
+			[]string{"album_artist", "title", "mood"},
+		},
+	}
+
+	for _, test := range tests {
+		gotTags, gotDropped := formatTags(test.format, source)
+		if !slices.Equal(gotTags, test.wantTags) {
+			t.Errorf("%s: tags = %+v, want %+v", test.format.Name, gotTags, test.wantTags)
+		}
+		if !slices.Equal(gotDropped, test.wantDropped) {
+			t.Errorf("%s: dropped = %q, want %q", test.format.Name, gotDropped, test.wantDropped)
+		}
+	}
+}
+
+func TestMetadataArgs(t *testing.T) {
+	action := &Action{tags: []tag{{Key: "title", Value: "Song"}}}
+	custom := config.OutputFormat{Name: "Custom", SupportsMetadata: true, TagStyle: config.TagStyleFfmpeg}
+
+	tests := []struct {
+		name   string
+		action *Action
+		format config.OutputFormat
+		want   []string
+	}{
+		{
+			"ID3",
+			action,
+			config.SupportedOutputFormats[0],
+			[]string{"-map_metadata", "-1", "-metadata", "title=Song", "-id3v2_version", "3"},
+		},
+		{
+			"ID3 in AIFF",
+			action,
+			config.SupportedOutputFormats[6],
+			[]string{"-map_metadata", "-1", "-metadata", "title=Song", "-id3v2_version", "3", "-write_id3v2", "1"},
+		},
+		{
+			"Vorbis comments in an Ogg stream",
+			action,
+			config.SupportedOutputFormats[7],
+			[]string{"-map_metadata", "-1", "-metadata:s:a:0", "TITLE=Song"},
+		},
+		{
+			"artwork keeps its metadata",
+			&Action{tags: action.tags, hasArtwork: true},
+			config.SupportedOutputFormats[1],
+			[]string{"-map_metadata", "-1", "-map_metadata:s:v", "0:s:v", "-metadata", "TITLE=Song"},
+		},
+		{
+			"format without tags",
+			action,
+			config.OutputFormat{Name: "No Tags"},
+			[]string{"-map_metadata", "-1"},
+		},
+		{
+			"custom format is left to FFmpeg",
+			action,
+			custom,
+			nil,
+		},
+		{
+			"custom format with tag changes",
+			&Action{tags: action.tags, TagChanges: []TagChange{{Tag: "title", NewValue: "Song"}}},
+			custom,
+			[]string{"-map_metadata", "-1", "-metadata", "title=Song"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := metadataArgs(test.action, test.format, 3); !slices.Equal(got, test.want) {
+			t.Errorf("%s: metadataArgs() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"github.com/termermc/your-loss-sync/config"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// findStaleFiles returns the files in the sync's destination directory that don't correspond to any of the specified
+// actions, which must be the planned actions of all source files that are included by the sync.
+// Outputs of files that are excluded by the sync's filter rules, file policy or conversion rules are stale too, so that
+// excluding files removes them from the destination.
+// The returned paths are relative to the sync's destination directory.
+func (p *Plan) findStaleFiles(fileActions []Action) ([]string, error) {
+	sync := p.Sync
+
+	// Collect every path that an included source file could have been synced to.
+	// Audio files may have been transcoded or copied raw by earlier syncs, and the output of a file that failed to be
+	// probed is unknown, so all possibilities are kept.
+	expected := make(map[string]struct{})
+	for _, action := range fileActions {
+		if action.DestRelative != "" {
+			expected[action.DestRelative] = struct{}{}
+		}
+		if action.SourceRelative == "" {
+			continue
+		}
+
+		destRelative := destRelativePath(sync, action.SourceRelative)
+		expected[destRelative] = struct{}{}
+		if isAudioPath(destRelative) {
+			expected[transcodedPath(destRelative, sync.Profile.OutputFormat)] = struct{}{}
+			for _, rule := range sync.ConversionRules {
+				if rule.Profile != nil {
+					expected[transcodedPath(destRelative, rule.Profile.OutputFormat)] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var stale []string
+	err := filepath.WalkDir(sync.DestDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The destination directory doesn't exist before the first sync, so nothing in it can be stale
+			if path == sync.DestDir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		destRelative, err := filepath.Rel(sync.DestDir, path)
+		if err != nil {
+			return err
+		}
+
+		if _, has := expected[destRelative]; !has {
+			stale = append(stale, destRelative)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+// deleteStaleFiles deletes the files of the specified ActionDelete actions from the sync's destination directory, along
+// with any directories left empty by their removal.
+// Stops early if ctx is cancelled.
+// Returns the number of files that were deleted.
+func deleteStaleFiles(ctx context.Context, s *AppState, sync *config.SyncConfig, actions []*Action) int {
+	deleted := 0
+
+	for _, action := range actions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		fullPath := filepath.Join(sync.DestDir, action.DestRelative)
+		err := os.Remove(fullPath)
+		if err != nil {
+			s.Events.Emit(Event{
+				Type:   EventError,
+				Sync:   sync,
+				Action: action,
+				Err:    err,
+			})
+			continue
+		}
+
+		deleted++
+		s.Events.Emit(Event{
+			Type:   EventFileDeleted,
+			Sync:   sync,
+			Action: action,
+		})
+
+		// Remove parent directories until one that is not empty is reached.
+		// Removing a non-empty directory fails, which is what stops the loop.
+		for dir := filepath.Dir(fullPath); dir != filepath.Clean(sync.DestDir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+
+	return deleted
+}
@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/termermc/your-loss-sync/config"
+	"github.com/termermc/your-loss-sync/lang"
+)
+
+// newTestState returns an app state with the default profiles and a temporary config directory.
+func newTestState(t *testing.T) *AppState {
+	t.Helper()
+
+	return &AppState{
+		Config:    &config.Config{Profiles: config.DefaultOutputProfiles},
+		ConfigDir: t.TempDir(),
+		Locale:    lang.NewLocale("en-us"),
+	}
+}
+
+// writeFiles creates files with the specified paths, relative to root, creating their parent directories.
+func writeFiles(t *testing.T, root string, paths ...string) {
+	t.Helper()
+
+	for _, path := range paths {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindStaleFiles(t *testing.T) {
+	destDir := t.TempDir()
+	writeFiles(t, destDir,
+		"notes.txt",
+		"old.txt",
+		"Album/01.mp3",
+		"Album/01.flac",
+		"Album/02.mp3",
+		"Album/03.mp3",
+	)
+
+	plan := &Plan{Sync: &config.SyncConfig{
+		SourceDir: t.TempDir(),
+		DestDir:   destDir,
+		Profile:   config.DefaultOutputProfiles[0],
+	}}
+	actions := []Action{
+		{Type: ActionCopy, SourceRelative: "notes.txt", DestRelative: "notes.txt"},
+		{Type: ActionTranscode, SourceRelative: filepath.Join("Album", "01.flac"), DestRelative: filepath.Join("Album", "01.mp3")},
+
+		// The output of a file that failed to be probed is unknown, so neither possible output is stale
+		{Type: ActionFail, SourceRelative: filepath.Join("Album", "02.flac")},
+	}
+
+	stale, err := plan.findStaleFiles(actions)
+	if err != nil {
+		t.Fatalf("findStaleFiles() error = %v", err)
+	}
+	slices.Sort(stale)
+
+	// Album/03.mp3 has no action, such as when its source was skipped by a conversion rule after being probed
+	want := []string{filepath.Join("Album", "03.mp3"), "old.txt"}
+	if !slices.Equal(stale, want) {
+		t.Errorf("findStaleFiles() = %q, want %q", stale, want)
+	}
+}
+
+func TestFindStaleFilesMissingDest(t *testing.T) {
+	for _, destDir := range []string{t.TempDir(), filepath.Join(t.TempDir(), "missing", "dest")} {
+		plan := &Plan{Sync: &config.SyncConfig{
+			SourceDir: t.TempDir(),
+			DestDir:   destDir,
+			Profile:   config.DefaultOutputProfiles[0],
+		}}
+
+		stale, err := plan.findStaleFiles([]Action{{Type: ActionCopy, SourceRelative: "a.txt", DestRelative: "a.txt"}})
+		if err != nil || len(stale) != 0 {
+			t.Errorf("findStaleFiles() into %q = %q, %v, want no stale files", destDir, stale, err)
+		}
+	}
+}
+
+func TestMirrorFirstSync(t *testing.T) {
+	s := newTestState(t)
+	srcDir := t.TempDir()
+	writeFiles(t, srcDir, "notes.txt", "Album/cover.jpg")
+
+	sync := &config.SyncConfig{
+		Name:       "first",
+		SourceDir:  srcDir,
+		DestDir:    filepath.Join(t.TempDir(), "missing", "dest"),
+		Profile:    config.DefaultOutputProfiles[0],
+		Mirror:     true,
+		FilePolicy: config.FilePolicyCopyAll,
+	}
+
+	status := StartSync(context.Background(), s, sync, func(files []string) bool {
+		t.Errorf("first sync tried to delete %q", files)
+		return false
+	})
+	if status != SyncStatusCompleted || s.Progress.Failed.Load() != 0 {
+		t.Fatalf("StartSync() = %d with %d failed files, want a completed sync", status, s.Progress.Failed.Load())
+	}
+
+	for _, path := range []string{"notes.txt", filepath.Join("Album", "cover.jpg")} {
+		if _, err := os.Stat(filepath.Join(sync.DestDir, path)); err != nil {
+			t.Errorf("%q was not synced: %v", path, err)
+		}
+	}
+
+	// Files that appear in the destination afterwards are stale
+	writeFiles(t, sync.DestDir, "stray.txt")
+	plan, err := PlanSync(context.Background(), s, sync)
+	if err != nil {
+		t.Fatalf("PlanSync() error = %v", err)
+	}
+	if deleted := plan.DeletedFiles(); !slices.Equal(deleted, []string{"stray.txt"}) {
+		t.Errorf("DeletedFiles() = %q, want only the stray file", deleted)
+	}
+}
@@ -0,0 +1,102 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// pauseController pauses and resumes a running sync.
+// The zero value is a controller that is not paused.
+type pauseController struct {
+	lock sync.Mutex
+
+	// Closed when the sync is resumed.
+	// Nil if the sync is not paused.
+	resumeChan chan struct{}
+
+	// The FFmpeg processes that are currently running.
+	// They are suspended while the sync is paused.
+	procs map[*os.Process]struct{}
+}
+
+// Pause pauses the sync and suspends running processes.
+// Returns false if the sync was already paused.
+func (p *pauseController) Pause() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.resumeChan != nil {
+		return false
+	}
+
+	p.resumeChan = make(chan struct{})
+	for proc := range p.procs {
+		// Failing to suspend a process only means that it keeps running until it finishes
+		_ = suspendProcess(proc)
+	}
+
+	return true
+}
+
+// Resume resumes the sync and any suspended processes.
+// Returns false if the sync was not paused.
+func (p *pauseController) Resume() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.resumeChan == nil {
+		return false
+	}
+
+	close(p.resumeChan)
+	p.resumeChan = nil
+	for proc := range p.procs {
+		_ = resumeProcess(proc)
+	}
+
+	return true
+}
+
+// Wait blocks while the sync is paused.
+// Returns the context's error if ctx is cancelled while waiting.
+func (p *pauseController) Wait(ctx context.Context) error {
+	p.lock.Lock()
+	resumeChan := p.resumeChan
+	p.lock.Unlock()
+
+	if resumeChan == nil {
+		return nil
+	}
+
+	select {
+	case <-resumeChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AddProcess registers a running process so that it is suspended while the sync is paused.
+// If the sync is already paused, the process is suspended immediately.
+func (p *pauseController) AddProcess(proc *os.Process) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.procs == nil {
+		p.procs = make(map[*os.Process]struct{})
+	}
+	p.procs[proc] = struct{}{}
+
+	if p.resumeChan != nil {
+		_ = suspendProcess(proc)
+	}
+}
+
+// RemoveProcess unregisters a process after it exited.
+func (p *pauseController) RemoveProcess(proc *os.Process) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.procs, proc)
+}
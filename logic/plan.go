@@ -0,0 +1,735 @@
+package logic
+
+import (
+	"context"
+	"github.com/termermc/your-loss-sync/config"
+	"github.com/termermc/your-loss-sync/lang"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ActionType is the type of action to perform on a file during a sync.
+type ActionType int
+
+const (
+	// ActionTranscode transcodes the source file using the sync's output profile.
+	ActionTranscode ActionType = iota
+
+	// ActionCopy copies the source file as-is.
+	ActionCopy
+
+	// ActionSkip leaves the destination file as-is.
+	ActionSkip
+
+	// ActionDelete deletes a file from the destination directory that no longer corresponds to any source file.
+	ActionDelete
+
+	// ActionFail marks a source file that could not be planned, such as a file that failed to be probed.
+	// Executing it counts as a failure.
+	ActionFail
+)
+
+// ActionReason is the reason why an action was planned.
+// Its value is a translation key.
+type ActionReason string
+
+const (
+	// ReasonNew means that the source file has not been synced before.
+	ReasonNew ActionReason = "sync.reason.new"
+
+	// ReasonSourceChanged means that the source file changed since it was last synced.
+	ReasonSourceChanged ActionReason = "sync.reason.source-changed"
+
+	// ReasonProfileChanged means that the output profile changed since the source file was last synced.
+	ReasonProfileChanged ActionReason = "sync.reason.profile-changed"
+
+	// ReasonOutputMissing means that the output of a previously synced file no longer exists.
+	ReasonOutputMissing ActionReason = "sync.reason.output-missing"
+
+	// ReasonUpToDate means that the output is up-to-date.
+	ReasonUpToDate ActionReason = "sync.reason.up-to-date"
+
+	// ReasonAlreadyExists means that the output already exists, but was not synced with a manifest.
+	// It is assumed to be up-to-date.
+	ReasonAlreadyExists ActionReason = "sync.reason.already-exists"
+
+	// ReasonStale means that the destination file no longer corresponds to any source file.
+	ReasonStale ActionReason = "sync.reason.stale"
+
+	// ReasonProbeFailed means that the source file could not be probed.
+	ReasonProbeFailed ActionReason = "sync.reason.probe-failed"
+)
+
+// Action is a single action of a sync plan.
+type Action struct {
+	// The type of action.
+	Type ActionType
+
+	// The reason why the action was planned.
+	Reason ActionReason
+
+	// The full path to the source file.
+	// Empty for ActionDelete.
+	SourcePath string
+
+	// The path to the source file, relative to the sync's source directory.
+	// Empty for ActionDelete.
+	SourceRelative string
+
+	// The path to the destination file, relative to the sync's destination directory.
+	// Empty for ActionFail.
+	DestRelative string
+
+	// The path to a previous output of the source file, relative to the sync's destination directory.
+	// If not empty, it will be deleted once the action succeeds.
+	ReplacesRelative string
+
+	// Whether an existing destination file will be overwritten.
+	Overwrite bool
+
+	// The name of the FFmpeg encoder to transcode with.
+	// Only set for ActionTranscode.
+	Encoder string
+
+	// The profile to transcode with, or whose artwork policy and ID3v2 version apply to a remuxed copy.
+	// Only set for ActionTranscode and remuxed copies.
+	Profile *config.OutputProfile
+
+	// The number of audio channels of the source file.
+	// 0 if the file was not probed, or if the number is unknown.
+	SourceChannels uint
+
+	// The number of audio channels of the output.
+	// Only set for ActionTranscode, and 0 if the number is unknown.
+	OutputChannels uint
+
+	// The names of the source file's tags that the output format can't hold.
+	// Only set for ActionTranscode and remuxed copies.
+	DroppedTags []string
+
+	// The changes that the sync's tag rules make to the source file's tags.
+	// Only set for ActionTranscode and remuxed copies.
+	TagChanges []TagChange
+
+	// The full path to the folder image that is embedded into the output as artwork.
+	// Only set for ActionTranscode and remuxed copies, if the sync embeds folder images and the source file has no
+	// embedded artwork.
+	ArtworkPath string
+
+	// The error that caused the action to fail.
+	// Only set for ActionFail.
+	Err error
+
+	// The source file's info.
+	// Nil for ActionDelete.
+	sourceInfo fs.FileInfo
+
+	// The source file's audio stream.
+	// Only set for ActionTranscode and remuxed copies.
+	audio sourceAudio
+
+	// The tags to write to the output, after applying the sync's tag rules.
+	// Only set for ActionTranscode and remuxed copies.
+	tags []tag
+
+	// Whether the output keeps the source file's embedded artwork.
+	// Only set for ActionTranscode and remuxed copies.
+	hasArtwork bool
+
+	// Whether an ActionCopy action remuxes the source file with FFmpeg to rewrite its tags or artwork, rather than
+	// copying it as-is.
+	// For ActionSkip, whether the existing output was remuxed.
+	remux bool
+}
+
+// describeChannels returns a human-readable description of the action's audio channels, prefixed with a space.
+// If the number of source channels is unknown, an empty string is returned.
+func (a Action) describeChannels(locale lang.Locale) string {
+	if a.SourceChannels == 0 {
+		return ""
+	}
+
+	srcChannels := strconv.Itoa(int(a.SourceChannels))
+	if a.Type == ActionTranscode && a.OutputChannels > 0 && a.OutputChannels != a.SourceChannels {
+		return " [" + locale.Tr("sync.channels.downmix", srcChannels, strconv.Itoa(int(a.OutputChannels))) + "]"
+	}
+
+	return " [" + locale.Tr("sync.channels", srcChannels) + "]"
+}
+
+// describeDroppedTags returns a human-readable list of the tags that the action drops, prefixed with a space.
+// If no tags are dropped, an empty string is returned.
+func (a Action) describeDroppedTags(locale lang.Locale) string {
+	if len(a.DroppedTags) == 0 {
+		return ""
+	}
+
+	return " [" + locale.Tr("sync.dropped-tags", strings.Join(a.DroppedTags, ", ")) + "]"
+}
+
+// maxTagChangeValueLen is the maximum number of characters of tag values shown in descriptions of tag changes.
+const maxTagChangeValueLen = 40
+
+// describeTagChanges returns a human-readable list of the action's tag changes, prefixed with a space.
+// If no tags are changed, an empty string is returned.
+func (a Action) describeTagChanges(locale lang.Locale) string {
+	if len(a.TagChanges) == 0 {
+		return ""
+	}
+
+	changes := make([]string, len(a.TagChanges))
+	for i, change := range a.TagChanges {
+		if change.NewValue == "" {
+			changes[i] = locale.Tr("sync.tag-change.removed", change.Tag)
+			continue
+		}
+
+		value := strings.Join(strings.Fields(change.NewValue), " ")
+		if runes := []rune(value); len(runes) > maxTagChangeValueLen {
+			value = string(runes[:maxTagChangeValueLen]) + "…"
+		}
+		changes[i] = locale.Tr("sync.tag-change.set", change.Tag, value)
+	}
+
+	return " [" + locale.Tr("sync.tag-changes", strings.Join(changes, ", ")) + "]"
+}
+
+// describeArtwork returns a human-readable description of the folder image embedded by the action, prefixed with a
+// space.
+// If no folder image is embedded, an empty string is returned.
+func (a Action) describeArtwork(locale lang.Locale) string {
+	if a.ArtworkPath == "" {
+		return ""
+	}
+
+	return " [" + locale.Tr("sync.artwork", filepath.Base(a.ArtworkPath)) + "]"
+}
+
+// describeDetails returns human-readable details of the action, prefixed with a space.
+func (a Action) describeDetails(locale lang.Locale) string {
+	return a.describeChannels(locale) + a.describeTagChanges(locale) + a.describeDroppedTags(locale) + a.describeArtwork(locale)
+}
+
+// Describe returns a human-readable description of the action.
+func (a Action) Describe(locale lang.Locale) string {
+	reason := locale.Tr(string(a.Reason))
+
+	switch a.Type {
+	case ActionTranscode:
+		if a.Overwrite {
+			return locale.Tr("sync.plan.transcode-overwrite", a.SourceRelative, a.DestRelative, a.Encoder, reason) + a.describeDetails(locale)
+		}
+		return locale.Tr("sync.plan.transcode", a.SourceRelative, a.DestRelative, a.Encoder, reason) + a.describeDetails(locale)
+	case ActionCopy:
+		if a.Overwrite {
+			return locale.Tr("sync.plan.copy-overwrite", a.SourceRelative, a.DestRelative, reason) + a.describeDetails(locale)
+		}
+		return locale.Tr("sync.plan.copy", a.SourceRelative, a.DestRelative, reason) + a.describeDetails(locale)
+	case ActionSkip:
+		return locale.Tr("sync.plan.skip", a.SourceRelative, reason)
+	case ActionDelete:
+		return locale.Tr("sync.plan.delete", a.DestRelative, reason)
+	default:
+		return locale.Tr("sync.plan.fail", a.SourceRelative, reason, locale.TrError(a.Err))
+	}
+}
+
+// Plan is the list of actions that a sync will perform.
+// Creating a plan does not modify the destination directory.
+type Plan struct {
+	// The sync the plan is for.
+	Sync *config.SyncConfig
+
+	// All planned actions.
+	// Deletions come first, followed by source files in the order they were found.
+	Actions []Action
+
+	// The output fingerprint of the sync when the plan was created.
+	// See outputFingerprint.
+	profFingerprint string
+
+	// The sync's manifest as it was when the plan was created.
+	manifest *Manifest
+
+	// The path to the sync's manifest.
+	manifestPath string
+
+	// Whether the plan only covers some of the sync's source files.
+	partial bool
+
+	// Suspends running probes while the sync is paused.
+	pause *pauseController
+
+	// The folder images found in source directories, keyed by directory path.
+	// See findFolderArtwork.
+	folderArtwork     map[string]string
+	folderArtworkLock sync.Mutex
+}
+
+// Count returns the number of actions with the specified type.
+func (p *Plan) Count(actionType ActionType) int {
+	count := 0
+	for _, action := range p.Actions {
+		if action.Type == actionType {
+			count++
+		}
+	}
+
+	return count
+}
+
+// DeletedFiles returns the paths of all files the plan will delete, relative to the sync's destination directory.
+func (p *Plan) DeletedFiles() []string {
+	var res []string
+	for _, action := range p.Actions {
+		if action.Type == ActionDelete {
+			res = append(res, action.DestRelative)
+		}
+	}
+
+	return res
+}
+
+// RemoveDeletes removes all ActionDelete actions from the plan.
+func (p *Plan) RemoveDeletes() {
+	actions := make([]Action, 0, len(p.Actions))
+	for _, action := range p.Actions {
+		if action.Type != ActionDelete {
+			actions = append(actions, action)
+		}
+	}
+
+	p.Actions = actions
+}
+
+// PlanSync scans a sync's source directory and determines which action to take for each file.
+// The destination directory is not modified.
+// If ctx is cancelled, planning stops and the context's error is returned.
+func PlanSync(ctx context.Context, s *AppState, sync *config.SyncConfig) (*Plan, error) {
+	return planSync(ctx, s, sync, nil)
+}
+
+// planSync creates a plan for a sync.
+// If srcPaths is not nil, only the source files with the specified full paths are planned, and no files are deleted.
+func planSync(ctx context.Context, s *AppState, sync *config.SyncConfig, srcPaths map[string]struct{}) (*Plan, error) {
+	// TODO FFmpeg setting
+	ffprobeBin := "ffprobe"
+
+	s.Events.Emit(Event{
+		Type: EventScanStarted,
+		Sync: sync,
+	})
+
+	manifestPath := GetManifestPath(s.ConfigDir, sync.Name)
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		// A broken manifest only means that unchanged files can't be skipped, so start over with an empty one
+		s.Events.Emit(Event{
+			Type: EventError,
+			Sync: sync,
+			Err:  err,
+		})
+		manifest = NewManifest()
+	}
+
+	plan := &Plan{
+		Sync:            sync,
+		profFingerprint: outputFingerprint(sync),
+		manifest:        manifest,
+		manifestPath:    manifestPath,
+		partial:         srcPaths != nil,
+		pause:           &s.pause,
+	}
+
+	var srcFiles []sourceFile
+	err = filepath.WalkDir(sync.SourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		if srcPaths != nil {
+			if _, has := srcPaths[path]; !has {
+				return nil
+			}
+		}
+
+		srcRelative, err := filepath.Rel(sync.SourceDir, path)
+		if err != nil {
+			return err
+		}
+		if !isSourceIncluded(sync, srcRelative) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		srcFiles = append(srcFiles, sourceFile{
+			Path: path,
+			Info: info,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Probing is slow, so plan files concurrently.
+	// Each worker writes to its own slots, so the order of the files is kept.
+	fileActions := make([]Action, len(srcFiles))
+	isIncluded := make([]bool, len(srcFiles))
+	indexChan := make(chan int, len(srcFiles))
+	for i := range srcFiles {
+		indexChan <- i
+	}
+	close(indexChan)
+
+	concurrency := s.Config.GetConcurrency(sync)
+	doneChan := make(chan struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer func() {
+				doneChan <- struct{}{}
+			}()
+
+			for idx := range indexChan {
+				if s.pause.Wait(ctx) != nil {
+					return
+				}
+
+				fileActions[idx], isIncluded[idx] = plan.planFile(ctx, ffprobeBin, srcFiles[idx])
+			}
+		}()
+	}
+	for i := 0; i < concurrency; i++ {
+		<-doneChan
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Drop files that turned out to be excluded by the file policy or conversion rules once they were probed
+	includedActions := make([]Action, 0, len(fileActions))
+	for i, action := range fileActions {
+		if isIncluded[i] {
+			includedActions = append(includedActions, action)
+		}
+	}
+	fileActions = includedActions
+
+	if sync.Mirror && !plan.partial {
+		staleFiles, err := plan.findStaleFiles(fileActions)
+		if err != nil {
+			return nil, err
+		}
+
+		// Previous outputs that are being replaced are only removed once their replacement was synced
+		replaced := make(map[string]struct{})
+		for _, action := range fileActions {
+			if action.ReplacesRelative != "" {
+				replaced[action.ReplacesRelative] = struct{}{}
+			}
+		}
+
+		for _, file := range staleFiles {
+			if _, has := replaced[file]; has {
+				continue
+			}
+
+			plan.Actions = append(plan.Actions, Action{
+				Type:         ActionDelete,
+				Reason:       ReasonStale,
+				DestRelative: file,
+			})
+		}
+	}
+
+	plan.Actions = append(plan.Actions, fileActions...)
+
+	return plan, nil
+}
+
+// shouldTranscode returns whether a source file with the specified audio stream should be transcoded with the specified
+// profile, rather than copied as-is.
+func shouldTranscode(sync *config.SyncConfig, prof *config.OutputProfile, audio sourceAudio) bool {
+	format := prof.OutputFormat
+
+	// Converting lossy audio to a lossless format only makes it bigger.
+	// Smart transcoding copies such sources as-is, even if they exceed the profile's limits, since reducing them would
+	// still produce a lossless file bigger than the source.
+	if sync.SmartTranscode && !audio.IsLossless && format.IsLossless {
+		return false
+	}
+
+	// Sources that exceed the profile's limits must always be transcoded, since the device may not play them otherwise
+	if prof.MaxSampleRate > 0 && audio.SampleRate > prof.MaxSampleRate {
+		return true
+	}
+	if format.IsLossless && prof.MaxBitDepth > 0 && audio.BitDepth > prof.MaxBitDepth {
+		return true
+	}
+	if audio.Channels > 0 && outputChannels(prof, audio) < audio.Channels {
+		return true
+	}
+
+	// If reencoding is disabled, files that are already in the output format are copied
+	if !sync.ReencodeSameFormat && format.Accepts(audio.Codec, audio.Containers) {
+		return false
+	}
+
+	if !sync.SmartTranscode || audio.IsLossless {
+		return true
+	}
+
+	// Sources that may not play on the device have to be transcoded, even if it costs quality
+	if !format.Accepts(audio.Codec, audio.Containers) && !slices.Contains(prof.CompatibleCodecs, audio.Codec) {
+		return true
+	}
+
+	// The bitrate of quality-based modes is only known after encoding, so there's no telling whether transcoding saves
+	// space
+	if prof.GetEncodingModeOptions().UsesQuality() {
+		return false
+	}
+
+	// Transcoding only saves space if the source's bitrate is higher than the target's.
+	// If the bitrate is unknown, transcode to be safe.
+	return audio.Bitrate == 0 || audio.Bitrate > prof.Bitrate
+}
+
+// outputFingerprint returns a fingerprint of all sync and profile settings that affect the output of transcoded files.
+// If it changes, files synced with the previous settings are outdated.
+func outputFingerprint(sync *config.SyncConfig) string {
+	res := sync.Profile.Fingerprint()
+
+	// Only appended when enabled so that files synced before the setting existed aren't considered outdated
+	if sync.SmartTranscode {
+		res += ":smart"
+
+		// Which lossy sources are copied depends on the codecs that each profile that may apply considers compatible
+		if len(sync.Profile.CompatibleCodecs) > 0 {
+			res += "|compat:" + strings.Join(sync.Profile.CompatibleCodecs, ",")
+		}
+		for i, rule := range sync.ConversionRules {
+			if rule.Action == config.ConversionTranscode && len(rule.Profile.CompatibleCodecs) > 0 {
+				res += "|compat" + strconv.Itoa(i) + ":" + strings.Join(rule.Profile.CompatibleCodecs, ",")
+			}
+		}
+	}
+
+	// Which rule applies to a file is only known once it was probed, so any rule change makes all files outdated
+	for _, rule := range sync.ConversionRules {
+		res += "|" + rule.Fingerprint()
+	}
+
+	// Only appended when there are rules, like the conversion rules above
+	for _, rule := range sync.TagRules {
+		res += "|tag:" + rule.String()
+	}
+
+	if sync.EmbedFolderArtwork {
+		res += "|art:" + strings.Join(sync.GetArtworkFilenames(), ",")
+	}
+
+	// The artwork of raw copies is encoded according to the profile that applies to them, even if its format has no
+	// artwork, so the artwork policy of every profile that may apply is included
+	if art := sync.Profile.ArtworkFingerprint(); art != "" {
+		res += "|copy-art:" + art
+	}
+	for _, rule := range sync.ConversionRules {
+		if rule.Action != config.ConversionTranscode {
+			continue
+		}
+		if art := rule.Profile.ArtworkFingerprint(); art != "" {
+			res += "|copy-art:" + art
+		}
+	}
+
+	return res
+}
+
+// planFile determines the action to take for a single source file.
+// Returns false if probing revealed that the file is excluded by the sync's file policy or conversion rules, in which
+// case the action's DestRelative is where the file would have been copied to.
+func (p *Plan) planFile(ctx context.Context, ffprobeBin string, srcFile sourceFile) (Action, bool) {
+	sync := p.Sync
+
+	srcRelative, err := filepath.Rel(sync.SourceDir, srcFile.Path)
+	if err != nil {
+		return Action{
+			Type:       ActionFail,
+			Reason:     ReasonProbeFailed,
+			SourcePath: srcFile.Path,
+			Err:        err,
+			sourceInfo: srcFile.Info,
+		}, true
+	}
+
+	action := Action{
+		SourcePath:     srcFile.Path,
+		SourceRelative: srcRelative,
+		sourceInfo:     srcFile.Info,
+	}
+
+	fileRelative := destRelativePath(sync, srcRelative)
+
+	// Skip the file without probing it if neither it nor the profile changed since it was last synced
+	entry, hasEntry := p.manifest.Get(srcRelative)
+	isSrcUnchanged := hasEntry && entry.Size == srcFile.Info.Size() && entry.ModTime.Equal(srcFile.Info.ModTime())
+	if isSrcUnchanged && entry.ProfileFingerprint == p.profFingerprint {
+		_, err := os.Stat(filepath.Join(sync.DestDir, entry.OutputPath))
+		if err == nil {
+			action.Type = ActionSkip
+			action.Reason = ReasonUpToDate
+			action.DestRelative = entry.OutputPath
+			action.remux = entry.IsRemuxed
+			return action, true
+		}
+	}
+
+	// Files are copied as-is unless they are audio files that need transcoding
+	action.Type = ActionCopy
+	action.DestRelative = fileRelative
+
+	if isAudioPath(fileRelative) {
+		res, err := doFfprobe(ctx, p.pause, ffprobeBin, srcFile.Path)
+		if err != nil {
+			action.Type = ActionFail
+			action.Reason = ReasonProbeFailed
+			action.DestRelative = ""
+			action.Err = err
+			return action, true
+		}
+
+		// Files without an audio stream are copied
+		audio, hasAudio := res.getAudio()
+		if !hasAudio && sync.FilePolicy != config.FilePolicyCopyAll {
+			return action, false
+		}
+
+		if hasAudio {
+			action.SourceChannels = audio.Channels
+
+			tags := res.getTags()
+			if len(sync.TagRules) > 0 {
+				tags, action.TagChanges = applyTagRules(sync.TagRules, tags)
+			}
+
+			// Existing embedded artwork is kept instead
+			var artworkPath string
+			if sync.EmbedFolderArtwork && !res.hasArtwork() {
+				artworkPath = p.findFolderArtwork(filepath.Dir(srcFile.Path))
+			}
+
+			prof := sync.Profile
+			isForcedCopy := false
+
+			ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileRelative), "."))
+			if rule := sync.GetConversionRule(audio.Codec, ext, audio.SampleRate, audio.BitDepth); rule != nil {
+				switch rule.Action {
+				case config.ConversionSkip:
+					return action, false
+				case config.ConversionCopy:
+					isForcedCopy = true
+				case config.ConversionTranscode:
+					prof = rule.Profile
+				}
+			}
+
+			if !isForcedCopy && shouldTranscode(sync, prof, audio) {
+				action.Type = ActionTranscode
+				action.DestRelative = transcodedPath(fileRelative, prof.OutputFormat)
+				action.Encoder = prof.OutputFormat.GetEncoder(outputBitDepth(prof, audio))
+				action.Profile = prof
+				action.OutputChannels = outputChannels(prof, audio)
+				action.audio = audio
+				action.tags = tags
+				action.hasArtwork = res.hasArtwork() && prof.KeepsArtwork()
+				_, action.DroppedTags = formatTags(prof.OutputFormat, action.tags)
+				if prof.KeepsArtwork() {
+					action.ArtworkPath = artworkPath
+				}
+			} else {
+				if !isForcedCopy && prof.OutputFormat.Accepts(audio.Codec, audio.Containers) {
+					// Files that are already in the output format get its extension, just like transcoded files
+					action.DestRelative = transcodedPath(fileRelative, prof.OutputFormat)
+				}
+
+				// Copies only need to be remuxed if the tag rules change their tags, if they get artwork, or if the
+				// profile's artwork policy changes their existing artwork.
+				// Files in containers that can't be written are copied as-is.
+				// Both folder images and existing artwork are encoded according to the profile.
+				tagFormat, ok := getTagFormat(audio)
+				keepsArtwork := tagFormat.SupportsArtwork && prof.ArtworkPolicy != config.ArtworkStrip
+				if !keepsArtwork {
+					artworkPath = ""
+				}
+				isArtworkChanged := res.hasArtwork() && prof.ArtworkPolicy != config.ArtworkCopy
+				if ok && (len(action.TagChanges) > 0 || artworkPath != "" || isArtworkChanged) {
+					action.remux = true
+					action.Profile = prof
+					action.audio = audio
+					action.tags = tags
+					action.hasArtwork = res.hasArtwork() && (keepsArtwork || prof.ArtworkPolicy == config.ArtworkCopy)
+					action.ArtworkPath = artworkPath
+					_, action.DroppedTags = formatTags(tagFormat, action.tags)
+				} else {
+					action.TagChanges = nil
+				}
+			}
+		}
+	}
+
+	if hasEntry && entry.OutputPath != action.DestRelative {
+		action.ReplacesRelative = entry.OutputPath
+	}
+
+	_, err = os.Stat(filepath.Join(sync.DestDir, action.DestRelative))
+	if err == nil {
+		// Files without a manifest entry were synced before manifests existed, so assume they're up-to-date.
+		// Raw copies don't depend on the profile, so they're only outdated if the source file changed, or if their tags
+		// are rewritten now or were before.
+		if !hasEntry {
+			action.Type = ActionSkip
+			action.Reason = ReasonAlreadyExists
+			return action, true
+		}
+		if action.Type == ActionCopy && isSrcUnchanged && action.ReplacesRelative == "" && !action.remux && !entry.IsRemuxed {
+			action.Type = ActionSkip
+			action.Reason = ReasonUpToDate
+			return action, true
+		}
+
+		action.Overwrite = true
+	}
+
+	switch {
+	case !hasEntry:
+		action.Reason = ReasonNew
+	case !isSrcUnchanged:
+		action.Reason = ReasonSourceChanged
+	case entry.ProfileFingerprint != p.profFingerprint:
+		action.Reason = ReasonProfileChanged
+	default:
+		action.Reason = ReasonOutputMissing
+	}
+
+	return action, true
+}
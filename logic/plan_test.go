@@ -0,0 +1,130 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/termermc/your-loss-sync/config"
+	"github.com/termermc/your-loss-sync/lang"
+)
+
+func TestShouldTranscode(t *testing.T) {
+	mp3 := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[0], Bitrate: 320000}
+	mp3Vbr := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[0], EncodingMode: config.EncodingVbr}
+	mp3Limited := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[0], Bitrate: 320000, MaxSampleRate: 44100}
+	aac := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[4], Bitrate: 224000}
+	aacCompat := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[4], Bitrate: 224000, CompatibleCodecs: []string{"mp3", "vorbis"}}
+	aacCompatStereo := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[4], Bitrate: 224000, CompatibleCodecs: []string{"mp3"}, ChannelPolicy: config.ChannelPolicyStereo}
+	flac := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[1], Quality: 5}
+	flacLimited := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[1], Quality: 5, MaxSampleRate: 48000, MaxBitDepth: 16}
+	wav24 := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[9]}
+
+	mp3Audio := func(bitrate uint) sourceAudio {
+		return sourceAudio{Codec: "mp3", Bitrate: bitrate, SampleRate: 44100, Channels: 2, Containers: []string{"mp3"}}
+	}
+	aacAudio := sourceAudio{Codec: "aac", Bitrate: 256000, SampleRate: 44100, Channels: 2, Containers: []string{"mov", "mp4", "m4a"}}
+	vorbisAudio := sourceAudio{Codec: "vorbis", Bitrate: 160000, SampleRate: 44100, Channels: 2, Containers: []string{"ogg"}}
+	wmaAudio := sourceAudio{Codec: "wmav2", Bitrate: 128000, SampleRate: 44100, Channels: 2, Containers: []string{"asf"}}
+	surroundMp3 := sourceAudio{Codec: "mp3", Bitrate: 128000, SampleRate: 44100, Channels: 6, Containers: []string{"mp3"}}
+	hiResMp3 := sourceAudio{Codec: "mp3", Bitrate: 128000, SampleRate: 48000, Channels: 2, Containers: []string{"mp3"}}
+	cdFlac := sourceAudio{Codec: "flac", IsLossless: true, SampleRate: 44100, BitDepth: 16, Channels: 2, Containers: []string{"flac"}}
+	cdWav := sourceAudio{Codec: "pcm_s16le", IsLossless: true, SampleRate: 44100, BitDepth: 16, Channels: 2, Containers: []string{"wav"}}
+	hiResWav := sourceAudio{Codec: "pcm_s24le", IsLossless: true, SampleRate: 96000, BitDepth: 24, Channels: 2, Containers: []string{"wav"}}
+	hiResFlac := sourceAudio{Codec: "flac", IsLossless: true, SampleRate: 96000, BitDepth: 24, Channels: 2, Containers: []string{"flac"}}
+
+	plain := &config.SyncConfig{}
+	smart := &config.SyncConfig{SmartTranscode: true}
+	reencode := &config.SyncConfig{ReencodeSameFormat: true}
+	smartReencode := &config.SyncConfig{SmartTranscode: true, ReencodeSameFormat: true}
+
+	tests := []struct {
+		name  string
+		sync  *config.SyncConfig
+		prof  *config.OutputProfile
+		audio sourceAudio
+		want  bool
+	}{
+		{"lossless to lossy", smart, mp3, cdFlac, true},
+		{"same format is copied", plain, mp3, mp3Audio(128000), false},
+		{"same format is reencoded", reencode, mp3, mp3Audio(128000), true},
+		{"same format at a lower bitrate is not upconverted", smartReencode, mp3, mp3Audio(128000), false},
+		{"same format at the same bitrate is not reencoded", smartReencode, mp3, mp3Audio(320000), false},
+		{"same format exceeding the sample rate limit", plain, mp3Limited, hiResMp3, true},
+		{"other lossy format without smart transcoding", plain, aac, mp3Audio(128000), true},
+		{"other lossy format is not compatible by default", smart, aac, mp3Audio(128000), true},
+		{"compatible lossy format at a lower bitrate", smart, aacCompat, mp3Audio(128000), false},
+		{"compatible lossy format at the same bitrate", smart, aacCompat, mp3Audio(224000), false},
+		{"compatible lossy format at a higher bitrate", smart, aacCompat, mp3Audio(320000), true},
+		{"compatible lossy format at an unknown bitrate", smart, aacCompat, mp3Audio(0), true},
+		{"incompatible lossy format into a quality mode", smart, mp3Vbr, aacAudio, true},
+		{"same lossy format into a quality mode", smartReencode, mp3Vbr, mp3Audio(320000), false},
+		{"lossy format that is only compatible with another profile", smart, aacCompatStereo, vorbisAudio, true},
+		{"incompatible lossy format", smart, aacCompat, wmaAudio, true},
+		{"compatible lossy format exceeding the channel limit", smart, aacCompatStereo, surroundMp3, true},
+		{"lossy to lossless without smart transcoding", plain, flac, mp3Audio(128000), true},
+		{"lossy to lossless is copied", smart, flac, mp3Audio(128000), false},
+		{"lossy to lossless is copied even beyond the limits", smart, flacLimited, hiResMp3, false},
+		{"lossless in the same format", smart, flac, cdFlac, false},
+		{"lossless in the same format is reencoded", smartReencode, flac, cdFlac, true},
+		{"lossless exceeding the limits", smart, flacLimited, hiResFlac, true},
+		{"lossless within the limits", plain, flacLimited, cdFlac, false},
+		{"16-bit PCM into 24-bit PCM", smart, wav24, cdWav, true},
+		{"24-bit PCM into 24-bit PCM", smart, wav24, hiResWav, false},
+	}
+
+	for _, test := range tests {
+		if got := shouldTranscode(test.sync, test.prof, test.audio); got != test.want {
+			t.Errorf("%s: shouldTranscode() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOutputFingerprintCompatibleCodecs(t *testing.T) {
+	prof := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[4], Bitrate: 224000}
+	compatProf := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[4], Bitrate: 224000, CompatibleCodecs: []string{"mp3"}}
+
+	plain := outputFingerprint(&config.SyncConfig{Profile: prof, SmartTranscode: true})
+	if strings.Contains(plain, "compat") {
+		t.Errorf("fingerprint without compatible codecs = %q", plain)
+	}
+
+	// Compatible codecs only matter with smart transcoding
+	if got := outputFingerprint(&config.SyncConfig{Profile: compatProf}); got != outputFingerprint(&config.SyncConfig{Profile: prof}) {
+		t.Errorf("compatible codecs changed the fingerprint without smart transcoding: %q", got)
+	}
+	if got := outputFingerprint(&config.SyncConfig{Profile: compatProf, SmartTranscode: true}); got == plain {
+		t.Errorf("compatible codecs did not change the fingerprint: %q", got)
+	}
+
+	// The compatible codecs of profiles used by conversion rules count as well
+	withRule := func(rule *config.OutputProfile) string {
+		return outputFingerprint(&config.SyncConfig{
+			Profile:         prof,
+			SmartTranscode:  true,
+			ConversionRules: []config.ConversionRule{{Codecs: []string{"mp3"}, Action: config.ConversionTranscode, Profile: rule}},
+		})
+	}
+	if withRule(prof) == withRule(compatProf) {
+		t.Error("compatible codecs of a conversion rule's profile did not change the fingerprint")
+	}
+}
+
+func TestActionDescribeChannels(t *testing.T) {
+	locale := lang.NewLocale("en-us")
+
+	tests := []struct {
+		action Action
+		want   string
+	}{
+		{Action{Type: ActionCopy}, ""},
+		{Action{Type: ActionCopy, SourceChannels: 6}, " [channels: 6]"},
+		{Action{Type: ActionTranscode, SourceChannels: 2, OutputChannels: 2}, " [channels: 2]"},
+		{Action{Type: ActionTranscode, SourceChannels: 6, OutputChannels: 2}, " [channels: 6 → 2]"},
+	}
+
+	for _, test := range tests {
+		if got := test.action.describeChannels(locale); got != test.want {
+			t.Errorf("describeChannels() of %+v = %q, want %q", test.action, got, test.want)
+		}
+	}
+}
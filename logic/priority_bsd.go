@@ -0,0 +1,19 @@
+//go:build darwin || dragonfly || freebsd || netbsd
+
+package logic
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// prepareLowPriority configures a command to run at a lowered priority before it is started.
+// On this platform, the priority can only be lowered after the process started, so this does nothing.
+func prepareLowPriority(_ *exec.Cmd) {}
+
+// lowerPriority lowers the CPU priority of a started process.
+// IO priority is not supported on this platform.
+func lowerPriority(proc *os.Process) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, proc.Pid, lowNiceValue)
+}
@@ -0,0 +1,38 @@
+package logic
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// prepareLowPriority configures a command to run at a lowered priority before it is started.
+// On Linux, the priority can only be lowered after the process started, so this does nothing.
+func prepareLowPriority(_ *exec.Cmd) {}
+
+// lowerPriority lowers the CPU and IO priority of a started process.
+func lowerPriority(proc *os.Process) error {
+	err := syscall.Setpriority(syscall.PRIO_PROCESS, proc.Pid, lowNiceValue)
+	if err != nil {
+		return err
+	}
+
+	// Only use idle disk time so that other programs writing to the same device aren't slowed down
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOPRIO_SET,
+		ioprioWhoProcess,
+		uintptr(proc.Pid),
+		ioprioClassIdle<<ioprioClassShift,
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
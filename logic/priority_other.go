@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !windows
+
+package logic
+
+import (
+	"os"
+	"os/exec"
+)
+
+// prepareLowPriority configures a command to run at a lowered priority before it is started.
+// Lowering priority is not supported on this platform, so this does nothing.
+func prepareLowPriority(_ *exec.Cmd) {}
+
+// lowerPriority lowers the priority of a started process.
+// Lowering priority is not supported on this platform, so this does nothing.
+func lowerPriority(_ *os.Process) error {
+	return nil
+}
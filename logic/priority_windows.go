@@ -0,0 +1,25 @@
+package logic
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// belowNormalPriorityClass is the BELOW_NORMAL_PRIORITY_CLASS process creation flag.
+const belowNormalPriorityClass = 0x00004000
+
+// prepareLowPriority configures a command to run at a lowered priority before it is started.
+func prepareLowPriority(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.CreationFlags |= belowNormalPriorityClass
+}
+
+// lowerPriority lowers the priority of a started process.
+// On Windows, the priority is set when the process is created, so this does nothing.
+func lowerPriority(_ *os.Process) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package logic
+
+import "os"
+
+// suspendProcess suspends a running process until resumeProcess is called.
+// Suspending processes is not supported on this platform, so the process keeps running.
+func suspendProcess(_ *os.Process) error {
+	return nil
+}
+
+// resumeProcess resumes a process suspended by suspendProcess.
+// Suspending processes is not supported on this platform, so this does nothing.
+func resumeProcess(_ *os.Process) error {
+	return nil
+}
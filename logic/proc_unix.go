@@ -0,0 +1,18 @@
+//go:build unix
+
+package logic
+
+import (
+	"os"
+	"syscall"
+)
+
+// suspendProcess suspends a running process until resumeProcess is called.
+func suspendProcess(proc *os.Process) error {
+	return proc.Signal(syscall.SIGSTOP)
+}
+
+// resumeProcess resumes a process suspended by suspendProcess.
+func resumeProcess(proc *os.Process) error {
+	return proc.Signal(syscall.SIGCONT)
+}
@@ -0,0 +1,195 @@
+package logic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStderrSize is the maximum number of bytes of a process's stderr that is kept.
+// FFmpeg prints its errors last, so the end of the output is kept.
+const maxStderrSize = 8 * 1024
+
+// CommandError is an error caused by an FFmpeg or FFprobe process that failed.
+type CommandError struct {
+	// The command line of the process, including the binary.
+	Command []string
+
+	// The process's exit code.
+	// -1 if the process could not be started or was killed.
+	ExitCode int
+
+	// The end of the process's stderr, trimmed of surrounding whitespace.
+	Stderr string
+
+	// The underlying error.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	msg := e.Err.Error()
+
+	// The last line of FFmpeg's output is usually the most descriptive
+	if e.Stderr != "" {
+		lines := strings.Split(e.Stderr, "\n")
+		msg += ": " + strings.TrimSpace(lines[len(lines)-1])
+	}
+
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// newCommandError creates a CommandError for a process that failed with the specified error.
+func newCommandError(cmd *exec.Cmd, stderr []byte, err error) *CommandError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &CommandError{
+		Command:  cmd.Args,
+		ExitCode: exitCode,
+		Stderr:   strings.TrimSpace(strings.ToValidUTF8(string(stderr), "")),
+		Err:      err,
+	}
+}
+
+// tailBuffer is a writer that only keeps the last bytes written to it, up to a maximum size.
+type tailBuffer struct {
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+
+	return len(p), nil
+}
+
+// Failure is a record of a file that failed to sync.
+type Failure struct {
+	// When the file failed.
+	Time time.Time `json:"time"`
+
+	// The full path to the source file.
+	SourcePath string `json:"sourcePath"`
+
+	// The command line of the process that failed, including the binary.
+	// Empty if the failure was not caused by a process.
+	Command []string `json:"command"`
+
+	// The exit code of the process that failed.
+	// -1 if the process could not be started or was killed, or if the failure was not caused by a process.
+	ExitCode int `json:"exitCode"`
+
+	// The end of the stderr of the process that failed, trimmed of surrounding whitespace.
+	Stderr string `json:"stderr"`
+
+	// The error message.
+	Error string `json:"error"`
+}
+
+// FailureReport is a report of all files that failed during a sync.
+// It is safe for concurrent use.
+type FailureReport struct {
+	// The name of the sync the report is for.
+	SyncName string `json:"syncName"`
+
+	// All failures, in the order they occurred.
+	Failures []Failure `json:"failures"`
+
+	lock sync.Mutex
+}
+
+// NewFailureReport creates a new empty failure report for the sync with the specified name.
+func NewFailureReport(syncName string) *FailureReport {
+	return &FailureReport{
+		SyncName: syncName,
+		Failures: make([]Failure, 0),
+	}
+}
+
+// Add adds a failure for the specified source file caused by err.
+// If err is or wraps a CommandError, its command line, exit code and stderr are included.
+func (r *FailureReport) Add(srcPath string, err error) {
+	failure := Failure{
+		Time:       time.Now(),
+		SourcePath: srcPath,
+		ExitCode:   -1,
+		Error:      err.Error(),
+	}
+
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		failure.Command = cmdErr.Command
+		failure.ExitCode = cmdErr.ExitCode
+		failure.Stderr = cmdErr.Stderr
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.Failures = append(r.Failures, failure)
+}
+
+// Len returns the number of failures in the report.
+func (r *FailureReport) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.Failures)
+}
+
+// WriteJson writes the report to the specified writer as JSON.
+func (r *FailureReport) WriteJson(writer io.Writer) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(r)
+}
+
+// WriteCsv writes the report to the specified writer as CSV, with a header row.
+// The command line is written as a single space-separated column.
+func (r *FailureReport) WriteCsv(writer io.Writer) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	csvWriter := csv.NewWriter(writer)
+
+	err := csvWriter.Write([]string{"time", "source_path", "command", "exit_code", "error", "stderr"})
+	if err != nil {
+		return err
+	}
+
+	for _, failure := range r.Failures {
+		err = csvWriter.Write([]string{
+			failure.Time.Format(time.RFC3339),
+			failure.SourcePath,
+			strings.Join(failure.Command, " "),
+			strconv.Itoa(failure.ExitCode),
+			failure.Error,
+			failure.Stderr,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
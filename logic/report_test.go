@@ -0,0 +1,183 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCommandError(t *testing.T) {
+	// The test binary exits with an error and prints its usage for unknown flags
+	cmd := exec.Command(os.Args[0], "-test.unknown-flag")
+	stderr := &tailBuffer{max: 16}
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err == nil {
+		t.Fatal("command did not fail")
+	}
+
+	cmdErr := newCommandError(cmd, stderr.data, err)
+	if cmdErr.ExitCode <= 0 {
+		t.Errorf("ExitCode = %d, want the exit code of the process", cmdErr.ExitCode)
+	}
+	if !slices.Equal(cmdErr.Command, cmd.Args) {
+		t.Errorf("Command = %q, want %q", cmdErr.Command, cmd.Args)
+	}
+	if len(cmdErr.Stderr) == 0 || len(cmdErr.Stderr) > 16 {
+		t.Errorf("Stderr = %q, want the end of the output", cmdErr.Stderr)
+	}
+	if !errors.Is(cmdErr, err) {
+		t.Error("CommandError does not unwrap to the process's error")
+	}
+
+	// Processes that couldn't be started have no exit code
+	cmd = exec.Command("/nonexistent/ffmpeg")
+	err = cmd.Run()
+	if cmdErr := newCommandError(cmd, nil, err); cmdErr.ExitCode != -1 {
+		t.Errorf("ExitCode of a process that was not started = %d, want -1", cmdErr.ExitCode)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	buf := &tailBuffer{max: 5}
+	for _, str := range []string{"ab", "cdef", "", "ghijkl"} {
+		n, err := buf.Write([]byte(str))
+		if n != len(str) || err != nil {
+			t.Errorf("Write(%q) = %d, %v", str, n, err)
+		}
+	}
+
+	if string(buf.data) != "hijkl" {
+		t.Errorf("data = %q, want %q", buf.data, "hijkl")
+	}
+}
+
+func TestCommandErrorMessage(t *testing.T) {
+	err := &CommandError{
+		ExitCode: 1,
+		Stderr:   "Input #0, flac\n  Duration: 00:03:00\nfile.flac: Invalid data found when processing input",
+		Err:      errors.New("exit status 1"),
+	}
+	if want := "exit status 1: file.flac: Invalid data found when processing input"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	err.Stderr = ""
+	if err.Error() != "exit status 1" {
+		t.Errorf("Error() without stderr = %q", err.Error())
+	}
+}
+
+// newTestReport returns a report with a failure caused by a process and one that was not.
+func newTestReport() *FailureReport {
+	report := NewFailureReport("My Sync")
+	report.Add("/music/a.flac", fmt.Errorf("transcoding: %w", &CommandError{
+		Command:  []string{"ffmpeg", "-i", "/music/a.flac", "out.mp3"},
+		ExitCode: 183,
+		Stderr:   "line 1\n\"quoted\", with comma",
+		Err:      errors.New("exit status 183"),
+	}))
+	report.Add("/music/b.txt", errors.New("disk full"))
+
+	return report
+}
+
+func TestFailureReportAdd(t *testing.T) {
+	report := newTestReport()
+	if report.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", report.Len())
+	}
+
+	cmdFailure := report.Failures[0]
+	if cmdFailure.ExitCode != 183 || cmdFailure.Stderr == "" || len(cmdFailure.Command) != 4 {
+		t.Errorf("failure caused by a wrapped CommandError = %+v, want its command, exit code and stderr", cmdFailure)
+	}
+	if cmdFailure.Time.IsZero() {
+		t.Error("failure time was not set")
+	}
+
+	otherFailure := report.Failures[1]
+	if otherFailure.ExitCode != -1 || otherFailure.Stderr != "" || otherFailure.Command != nil || otherFailure.Error != "disk full" {
+		t.Errorf("failure not caused by a process = %+v", otherFailure)
+	}
+}
+
+func TestFailureReportWriteJson(t *testing.T) {
+	report := newTestReport()
+
+	buf := &bytes.Buffer{}
+	if err := report.WriteJson(buf); err != nil {
+		t.Fatalf("WriteJson() error = %v", err)
+	}
+
+	var decoded struct {
+		SyncName string    `json:"syncName"`
+		Failures []Failure `json:"failures"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJson() wrote invalid JSON: %v", err)
+	}
+
+	if decoded.SyncName != report.SyncName || len(decoded.Failures) != len(report.Failures) {
+		t.Fatalf("WriteJson() = %+v", decoded)
+	}
+	for i, got := range decoded.Failures {
+		want := report.Failures[i]
+		if !got.Time.Equal(want.Time) || got.SourcePath != want.SourcePath || !slices.Equal(got.Command, want.Command) ||
+			got.ExitCode != want.ExitCode || got.Stderr != want.Stderr || got.Error != want.Error {
+			t.Errorf("failure %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	// An empty report has an empty list rather than null
+	buf.Reset()
+	if err := NewFailureReport("Empty").WriteJson(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"failures": []`) {
+		t.Errorf("WriteJson() of an empty report = %s", buf.String())
+	}
+}
+
+func TestFailureReportWriteCsv(t *testing.T) {
+	report := newTestReport()
+
+	buf := &bytes.Buffer{}
+	if err := report.WriteCsv(buf); err != nil {
+		t.Fatalf("WriteCsv() error = %v", err)
+	}
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("WriteCsv() wrote invalid CSV: %v", err)
+	}
+
+	want := [][]string{
+		{"time", "source_path", "command", "exit_code", "error", "stderr"},
+		{
+			report.Failures[0].Time.Format(time.RFC3339),
+			"/music/a.flac",
+			"ffmpeg -i /music/a.flac out.mp3",
+			"183",
+			report.Failures[0].Error,
+			"line 1\n\"quoted\", with comma",
+		},
+		{report.Failures[1].Time.Format(time.RFC3339), "/music/b.txt", "", "-1", "disk full", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("WriteCsv() wrote %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if !slices.Equal(records[i], want[i]) {
+			t.Errorf("record %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"github.com/termermc/your-loss-sync/config"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// classifyError returns the retry error class of an error that caused a file to fail.
+// If the error does not belong to any class, false is returned.
+func classifyError(err error) (config.RetryErrorClass, bool) {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			// The process could not be started, which retrying won't fix
+			return "", false
+		}
+
+		// Processes killed by a signal have an exit code of -1.
+		// On Windows, crashes exit with an NTSTATUS code, which is larger than any regular exit code.
+		if cmdErr.ExitCode < 0 || cmdErr.ExitCode > 255 {
+			return config.RetryErrorFfmpegCrash, true
+		}
+
+		return config.RetryErrorFfmpegFailed, true
+	}
+
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	if errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &syscallErr) {
+		return config.RetryErrorIo, true
+	}
+
+	return "", false
+}
+
+// waitBackoff waits for the specified duration.
+// Returns the context's error if ctx is cancelled before the duration has passed.
+func waitBackoff(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RetryFailed plans and executes a sync for only the files that failed in the specified report.
+// The sync should be the one the report was created for.
+// Files that no longer exist are ignored.
+// Progress is reported through the state's event bus.
+// The sync can be cancelled by cancelling ctx, which kills any running FFmpeg processes.
+// This function blocks until the sync is complete or cancelled, and returns its final status.
+func RetryFailed(ctx context.Context, s *AppState, sync *config.SyncConfig, report *FailureReport) SyncStatus {
+	beginSync(s, sync)
+
+	report.lock.Lock()
+	srcPaths := make(map[string]struct{}, len(report.Failures))
+	for _, failure := range report.Failures {
+		srcPaths[failure.SourcePath] = struct{}{}
+	}
+	report.lock.Unlock()
+
+	plan, err := planSync(ctx, s, sync, srcPaths)
+	if err != nil {
+		return abortSync(ctx, s, sync, err)
+	}
+
+	return ExecutePlan(ctx, s, plan)
+}
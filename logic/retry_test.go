@@ -0,0 +1,55 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/termermc/your-loss-sync/config"
+)
+
+func TestClassifyError(t *testing.T) {
+	exitErr := &exec.ExitError{}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantClass config.RetryErrorClass
+		wantOk    bool
+	}{
+		{"ffmpeg exit code", &CommandError{ExitCode: 1, Err: exitErr}, config.RetryErrorFfmpegFailed, true},
+		{"wrapped ffmpeg exit code", fmt.Errorf("transcoding: %w", &CommandError{ExitCode: 183, Err: exitErr}), config.RetryErrorFfmpegFailed, true},
+		{"killed by a signal", &CommandError{ExitCode: -1, Err: exitErr}, config.RetryErrorFfmpegCrash, true},
+		{"windows crash", &CommandError{ExitCode: 0x40010004, Err: exitErr}, config.RetryErrorFfmpegCrash, true},
+		{"not started", &CommandError{ExitCode: -1, Err: exec.ErrNotFound}, "", false},
+		{"path error", &fs.PathError{Op: "open", Path: "a.flac", Err: fs.ErrPermission}, config.RetryErrorIo, true},
+		{"link error", fmt.Errorf("renaming: %w", &os.LinkError{Op: "rename", Old: "a", New: "b", Err: fs.ErrExist}), config.RetryErrorIo, true},
+		{"syscall error", os.NewSyscallError("fsync", errors.New("input/output error")), config.RetryErrorIo, true},
+		{"other error", errors.New("something else"), "", false},
+		{"cancelled", context.Canceled, "", false},
+	}
+
+	for _, test := range tests {
+		class, ok := classifyError(test.err)
+		if class != test.wantClass || ok != test.wantOk {
+			t.Errorf("%s: classifyError() = %q, %v, want %q, %v", test.name, class, ok, test.wantClass, test.wantOk)
+		}
+	}
+}
+
+func TestWaitBackoff(t *testing.T) {
+	if err := waitBackoff(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("waitBackoff() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitBackoff(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitBackoff() with a cancelled context = %v, want context.Canceled", err)
+	}
+}
@@ -1,7 +1,10 @@
 package logic
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/termermc/your-loss-sync/config"
 	"github.com/termermc/your-loss-sync/util"
 	"io"
@@ -9,33 +12,166 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 )
 
+type ffprobeStream struct {
+	CodecType        string            `json:"codec_type"` // We're looking for "audio"
+	CodecName        string            `json:"codec_name"`
+	BitRate          string            `json:"bit_rate"` // Missing for some formats
+	SampleRate       string            `json:"sample_rate"`
+	BitsPerSample    int               `json:"bits_per_sample"`     // Set for PCM codecs
+	BitsPerRawSample string            `json:"bits_per_raw_sample"` // Set for other lossless codecs
+	Channels         int               `json:"channels"`
+	Tags             map[string]string `json:"tags"` // Some containers such as Ogg store tags in the stream
+}
+
 type ffprobeResult struct {
-	Streams []struct {
-		CodecType string `json:"codec_type"` // We're looking for "audio"
-		CodecName string `json:"codec_name"`
-	} `json:"streams"`
+	Streams []ffprobeStream `json:"streams"`
+	Format  struct {
+		FormatName string            `json:"format_name"` // May contain several comma-separated names
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// sourceAudio describes the audio stream of a source file.
+type sourceAudio struct {
+	// The FFmpeg name of the stream's codec.
+	Codec string
+
+	// The stream's bitrate.
+	// 0 if unknown.
+	Bitrate uint
+
+	// Whether the codec is lossless.
+	IsLossless bool
+
+	// The stream's sample rate in Hz.
+	// 0 if unknown.
+	SampleRate uint
+
+	// The stream's bit depth.
+	// 0 if unknown, or if the codec is lossy.
+	BitDepth uint
+
+	// The stream's number of channels.
+	// 0 if unknown.
+	Channels uint
+
+	// The FFprobe format names of the file's container.
+	Containers []string
+}
+
+// getAudio returns the first audio stream of the probed file.
+// If the file has no audio stream, false is returned.
+func (r ffprobeResult) getAudio() (sourceAudio, bool) {
+	for _, stream := range r.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+
+		// The format's bitrate includes other streams such as artwork, so only fall back to it
+		bitrate, err := strconv.ParseUint(stream.BitRate, 10, 0)
+		if err != nil {
+			bitrate, err = strconv.ParseUint(r.Format.BitRate, 10, 0)
+			if err != nil {
+				bitrate = 0
+			}
+		}
+
+		sampleRate, err := strconv.ParseUint(stream.SampleRate, 10, 0)
+		if err != nil {
+			sampleRate = 0
+		}
+
+		isLossless := isLosslessCodec(stream.CodecName)
+
+		// Lossy codecs may still report the bit depth they decode to, which says nothing about their quality
+		var bitDepth uint
+		if isLossless {
+			rawBitDepth, err := strconv.ParseUint(stream.BitsPerRawSample, 10, 0)
+			if err == nil && rawBitDepth > 0 {
+				bitDepth = uint(rawBitDepth)
+			} else if stream.BitsPerSample > 0 {
+				bitDepth = uint(stream.BitsPerSample)
+			}
+		}
+
+		return sourceAudio{
+			Codec:      stream.CodecName,
+			Bitrate:    uint(bitrate),
+			IsLossless: isLossless,
+			SampleRate: uint(sampleRate),
+			BitDepth:   bitDepth,
+			Channels:   uint(max(stream.Channels, 0)),
+			Containers: strings.Split(r.Format.FormatName, ","),
+		}, true
+	}
+
+	return sourceAudio{}, false
+}
+
+// hasArtwork returns whether the probed file has embedded artwork.
+// Audio containers store artwork as a video stream.
+func (r ffprobeResult) hasArtwork() bool {
+	for _, stream := range r.Streams {
+		if stream.CodecType == "video" {
+			return true
+		}
+	}
+
+	return false
 }
 
-func doFfprobe(bin string, filePath string) (ffprobeResult, error) {
-	cmd := exec.Command(
+// losslessCodecs are the FFmpeg names of lossless audio codecs, apart from PCM codecs.
+var losslessCodecs = []string{
+	"flac",
+	"alac",
+	"ape",
+	"wavpack",
+	"tta",
+	"tak",
+	"shorten",
+	"mlp",
+	"truehd",
+	"wmalossless",
+}
+
+// isLosslessCodec returns whether the codec with the specified FFmpeg name is lossless.
+func isLosslessCodec(codec string) bool {
+	return strings.HasPrefix(codec, "pcm_") || slices.Contains(losslessCodecs, codec)
+}
+
+// doFfprobe probes a file with FFprobe.
+// The process is registered with pause so that it is suspended while the sync is paused.
+func doFfprobe(ctx context.Context, pause *pauseController, bin string, filePath string) (ffprobeResult, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		bin,
 		"-print_format", "json",
 		"-show_streams",
+		"-show_format",
 		filePath,
 	)
-	out, err := cmd.Output()
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	stderr := &tailBuffer{max: maxStderrSize}
+	cmd.Stderr = stderr
+	err := cmd.Start()
+	if err == nil {
+		pause.AddProcess(cmd.Process)
+		err = cmd.Wait()
+		pause.RemoveProcess(cmd.Process)
+	}
 	if err != nil {
-		return ffprobeResult{}, err
+		return ffprobeResult{}, newCommandError(cmd, stderr.data, err)
 	}
 
 	var res ffprobeResult
-	err = json.Unmarshal(out, &res)
+	err = json.Unmarshal(stdout.Bytes(), &res)
 	if err != nil {
 		return ffprobeResult{}, err
 	}
@@ -54,242 +190,440 @@ var audioExtensions = []string{
 	"ape",
 	"wma",
 	"ogg",
+	"wv",
 }
 
-// StartSync starts a sync.
-// This function blocks until the sync is complete.
-func StartSync(s *AppState, sync *config.SyncConfig, logOut chan string) {
+// lowNiceValue is the nice value of FFmpeg processes that run at a lowered priority.
+const lowNiceValue = 10
+
+// sourceFile is a file in a sync's source directory.
+type sourceFile struct {
+	// The full path to the file.
+	Path string
+
+	// The file's info.
+	Info fs.FileInfo
+}
+
+// contextReader is a reader that stops reading once its context is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
+
+// destRelativePath returns the destination path of a file relative to the sync's destination directory.
+// The path is escaped if the sync is configured to escape filenames.
+// Does not take into account extension changes caused by transcoding.
+func destRelativePath(sync *config.SyncConfig, srcRelative string) string {
+	if !sync.EscapeFilenames {
+		return srcRelative
+	}
+
+	pathParts := strings.Split(srcRelative, string(os.PathSeparator))
+	for i := range pathParts {
+		pathParts[i] = util.EscapeFilename(pathParts[i])
+	}
+
+	return strings.Join(pathParts, string(os.PathSeparator))
+}
+
+// transcodedPath returns the path with its extension replaced by the extension of the specified output format.
+func transcodedPath(path string, format config.OutputFormat) string {
+	return path[:len(path)-len(filepath.Ext(path))] + "." + format.Extension
+}
+
+// isAudioPath returns whether the path has the extension of a supported audio file.
+func isAudioPath(path string) bool {
+	ext := filepath.Ext(path)
+	return ext != "" && slices.Contains(audioExtensions, strings.ToLower(ext[1:]))
+}
+
+// isSourceIncluded returns whether a source file is synced according to the sync's filter rules and file policy.
+// Audio files without an audio stream can only be told apart by probing them, so they are included here.
+func isSourceIncluded(sync *config.SyncConfig, srcRelative string) bool {
+	if !sync.IsIncluded(filepath.ToSlash(srcRelative)) {
+		return false
+	}
+
+	switch sync.FilePolicy {
+	case config.FilePolicyAudioOnly:
+		return isAudioPath(srcRelative)
+	case config.FilePolicyAudioAndSidecars:
+		if isAudioPath(srcRelative) {
+			return true
+		}
+
+		ext := filepath.Ext(srcRelative)
+		return ext != "" && slices.Contains(sync.GetSidecarExtensions(), strings.ToLower(ext[1:]))
+	default:
+		return true
+	}
+}
+
+// StartSync plans and executes a sync.
+// If the plan deletes files, confirmDelete is called with their paths, relative to the destination directory.
+// The files are only deleted if it returns true.
+// If confirmDelete is nil, no files are deleted.
+// Progress is reported through the state's event bus.
+// The sync can be cancelled by cancelling ctx, which kills any running FFmpeg processes.
+// This function blocks until the sync is complete or cancelled, and returns its final status.
+func StartSync(ctx context.Context, s *AppState, sync *config.SyncConfig, confirmDelete func(files []string) bool) SyncStatus {
+	beginSync(s, sync)
+
+	plan, err := PlanSync(ctx, s, sync)
+	if err != nil {
+		return abortSync(ctx, s, sync, err)
+	}
+
+	if deleted := plan.DeletedFiles(); len(deleted) > 0 {
+		if confirmDelete == nil || !confirmDelete(deleted) {
+			plan.RemoveDeletes()
+		}
+	}
+
+	return ExecutePlan(ctx, s, plan)
+}
+
+// beginSync resets the progress for a sync that is about to be planned.
+func beginSync(s *AppState, sync *config.SyncConfig) {
 	s.Progress.Sync.Store(sync)
+	s.Progress.Status.Store(int32(SyncStatusRunning))
 	s.Progress.Completed.Store(0)
 	s.Progress.Total.Store(0)
 	s.Progress.Failed.Store(0)
+	s.Progress.Report.Store(nil)
+}
+
+// abortSync finishes a sync whose planning failed with the specified error, and returns its final status.
+func abortSync(ctx context.Context, s *AppState, sync *config.SyncConfig, err error) SyncStatus {
+	status := SyncStatusCompleted
+	if ctx.Err() != nil {
+		s.pause.Resume()
+		status = SyncStatusCancelled
+	} else {
+		s.Events.Emit(Event{
+			Type: EventError,
+			Sync: sync,
+			Err:  err,
+		})
+	}
+
+	s.Progress.Status.Store(int32(status))
+	s.Progress.Sync.Store(nil)
+	s.Events.Emit(Event{
+		Type: EventSyncFinished,
+		Sync: sync,
+		Totals: SyncTotals{
+			Status: status,
+		},
+	})
+	return status
+}
+
+// ExecutePlan executes a sync plan.
+// Progress is reported through the state's event bus.
+// The sync can be cancelled by cancelling ctx, which kills any running FFmpeg processes.
+// This function blocks until the sync is complete or cancelled, and returns its final status.
+func ExecutePlan(ctx context.Context, s *AppState, plan *Plan) SyncStatus {
+	sync := plan.Sync
+
+	s.Progress.Sync.Store(sync)
+	if s.SyncStatus() != SyncStatusPaused {
+		// The sync may have been paused while it was being planned
+		s.Progress.Status.Store(int32(SyncStatusRunning))
+	}
+	s.Progress.Completed.Store(0)
+	s.Progress.Total.Store(int64(len(plan.Actions) - plan.Count(ActionDelete)))
+	s.Progress.Failed.Store(0)
+
+	report := NewFailureReport(sync.Name)
+	s.Progress.Report.Store(report)
 
-	checkErr := func(err error) bool {
+	emit := func(eventType EventType, action *Action) {
+		s.Events.Emit(Event{
+			Type:   eventType,
+			Sync:   sync,
+			Action: action,
+		})
+	}
+
+	checkErr := func(action *Action, err error) bool {
 		if err == nil {
 			return false
 		}
 
-		logOut <- s.Locale.Tr("general.error") + ": " + s.Locale.TrError(err)
+		// Errors caused by cancellation, such as killed FFmpeg processes, are not failures
+		if ctx.Err() != nil {
+			return true
+		}
+
 		s.Progress.Failed.Add(1)
+		report.Add(action.SourcePath, err)
+		s.Events.Emit(Event{
+			Type:   EventFileFailed,
+			Sync:   sync,
+			Action: action,
+			Err:    err,
+		})
 		return true
 	}
 
 	// TODO FFmpeg setting
 	ffmpegBin := "ffmpeg"
-	ffprobeBin := "ffprobe"
-
-	logOut <- s.Locale.Tr("sync.scanning-source")
 
-	srcPath := sync.SourceDir
-	if !strings.HasSuffix(srcPath, "/") {
-		srcPath += "/"
-	}
 	destPath := sync.DestDir
-	if !strings.HasSuffix(destPath, "/") {
-		destPath += "/"
+
+	var deleteActions []*Action
+	actionChan := make(chan *Action, len(plan.Actions))
+	for i := range plan.Actions {
+		action := &plan.Actions[i]
+		if action.Type == ActionDelete {
+			deleteActions = append(deleteActions, action)
+		} else {
+			actionChan <- action
+			emit(EventFileQueued, action)
+		}
 	}
+	close(actionChan)
 
-	// Assume that transcoding a file maxes out a single CPU thread
-	concurrency := runtime.NumCPU()
-	if concurrency < 1 {
-		concurrency = 1
+	if len(deleteActions) > 0 {
+		deleteStaleFiles(ctx, s, sync, deleteActions)
 	}
 
-	fileChan := make(chan string, 100_000) // Arbitrarily large buffer since we want to calculate the total number of files
-	doneChan := make(chan struct{}, concurrency)
+	// syncFile transcodes or copies the file of an action.
+	// Temporary files are cleaned up if it fails.
+	syncFile := func(action *Action) error {
+		destFilePath := filepath.Join(destPath, action.DestRelative)
 
-	for i := 0; i < concurrency; i++ {
-		go func() {
-			defer func() {
-				doneChan <- struct{}{}
-			}()
+		// Make dirs
+		err := os.MkdirAll(filepath.Dir(destFilePath), os.ModePerm)
+		if err != nil {
+			return err
+		}
 
-			for srcFilePathFull := range fileChan {
-				if s.Progress.Sync.Load() != sync {
-					// Sync has been canceled
-					return
+		if action.Type == ActionTranscode || action.remux {
+			// FFmpeg picks the container by the extension, so the temporary file needs one
+			var destTmpPath string
+			var args []string
+			if action.Type == ActionTranscode {
+				destTmpPath = destFilePath + ".tmp." + action.Profile.OutputFormat.Extension
+				args = transcodeArgs(action, destTmpPath)
+				emit(EventTranscodeStarted, action)
+			} else {
+				destTmpPath = destFilePath + ".tmp" + filepath.Ext(destFilePath)
+				args = remuxArgs(action, destTmpPath)
+				emit(EventCopyStarted, action)
+			}
+
+			// Run FFmpeg
+			cmd := exec.CommandContext(ctx, ffmpegBin, args...)
+			stderr := &tailBuffer{max: maxStderrSize}
+			cmd.Stderr = stderr
+			if sync.LowPriority {
+				prepareLowPriority(cmd)
+			}
+			err = cmd.Start()
+			if err == nil {
+				if sync.LowPriority {
+					// Failing to lower the priority is not a reason to fail the file
+					_ = lowerPriority(cmd.Process)
 				}
 
-				fileRelative := srcFilePathFull[len(srcPath):]
+				s.pause.AddProcess(cmd.Process)
+				err = cmd.Wait()
+				s.pause.RemoveProcess(cmd.Process)
+			}
+			if err != nil {
+				_ = os.Remove(destTmpPath)
+				return newCommandError(cmd, stderr.data, err)
+			}
 
-				pathParts := strings.Split(fileRelative, string(os.PathSeparator))
+			// Successfully transcoded or remuxed, rename the tmp file
+			err = os.Rename(destTmpPath, destFilePath)
+			if err != nil {
+				_ = os.Remove(destTmpPath)
+				return err
+			}
 
-				// Escape parts of the srcFilePathFull path if specified
-				if sync.EscapeFilenames {
-					for i, _ := range pathParts {
-						pathParts[i] = util.EscapeFilename(pathParts[i])
-					}
+			return nil
+		}
 
-					fileRelative = strings.Join(pathParts, string(os.PathSeparator))
-				}
+		destTmpPath := destFilePath + ".tmp"
 
-				fnameFull := pathParts[len(pathParts)-1]
-				ext := filepath.Ext(fnameFull)
-				fnameNoExt := fnameFull[:len(fnameFull)-len(ext)]
+		emit(EventCopyStarted, action)
 
-				// Make dirs
-				if len(pathParts) > 1 {
-					err := os.MkdirAll(filepath.Join(destPath, filepath.Dir(fileRelative)), os.ModePerm)
-					if checkErr(err) {
-						continue
-					}
-				}
+		// Simply copy the file
+		osSrcFile, err := os.Open(action.SourcePath)
+		if err != nil {
+			return err
+		}
+		osDestFile, err := os.Create(destTmpPath)
+		if err != nil {
+			_ = osSrcFile.Close()
+			return err
+		}
+		_, err = io.Copy(osDestFile, contextReader{ctx: ctx, reader: osSrcFile})
+		_ = osDestFile.Close()
+		_ = osSrcFile.Close()
+		if err != nil {
+			_ = os.Remove(destTmpPath)
+			return err
+		}
 
-				shouldCopyRaw := true
+		// Successfully copied, rename the tmp file
+		err = os.Rename(destTmpPath, destFilePath)
+		if err != nil {
+			_ = os.Remove(destTmpPath)
+			return err
+		}
 
-				// Check if the srcFilePathFull is a supported audio srcFilePathFull
-				if ext != "" && slices.Contains(audioExtensions, strings.ToLower(ext[1:])) {
-					prof := sync.Profile
+		return nil
+	}
 
-					destFilePath := filepath.Join(destPath, filepath.Dir(fileRelative), fnameNoExt+"."+prof.OutputFormat.Extension)
-					destTmpPath := destFilePath + ".tmp." + prof.OutputFormat.Extension
+	retryPolicy := s.Config.RetryPolicy
 
-					// Check if it already exists
-					_, err := os.Stat(destFilePath)
-					if err == nil {
-						println(s.Locale.Tr("sync.path-already-exists", fileRelative))
-						s.Progress.Completed.Add(1)
-						continue
-					}
+	concurrency := s.Config.GetConcurrency(sync)
 
-					// Probe the file
-					res, err := doFfprobe(ffprobeBin, srcFilePathFull)
-					if checkErr(err) {
-						continue
-					}
+	doneChan := make(chan struct{}, concurrency)
 
-					// Check for audio stream
-					var audioFmt string
-					for _, stream := range res.Streams {
-						if stream.CodecType == "audio" {
-							audioFmt = stream.CodecName
-							break
-						}
-					}
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer func() {
+				doneChan <- struct{}{}
+			}()
 
-					if audioFmt == "" {
-						// No audio stream, copy the file
-						shouldCopyRaw = true
-					} else if !sync.ReencodeSameFormat && strings.Contains(sync.Profile.OutputFormat.FfmpegEncoder, audioFmt) {
-						// Reencoding is disabled and the audio format matches the output format, copy the file
-						shouldCopyRaw = true
-					} else {
-						// The file needs to be encoded
-						shouldCopyRaw = false
-
-						println(s.Locale.Tr("sync.transcoding", fileRelative))
-
-						// Run FFmpeg
-						cmd := exec.Command(
-							ffmpegBin,
-							"-i", srcFilePathFull,
-							"-c:v", "copy",
-							"-c:a", prof.OutputFormat.FfmpegEncoder,
-							"-b:a", strconv.Itoa(int(prof.OutputFormat.SuggestedBitrate)),
-							destTmpPath,
-							"-y",
-						)
-						err = cmd.Run()
-						if checkErr(err) {
-							_ = os.Remove(destTmpPath)
-
-							continue
-						}
+			for action := range actionChan {
+				if s.pause.Wait(ctx) != nil {
+					// Sync has been cancelled
+					return
+				}
 
-						// Successfully transcoded, rename the tmp file
-						err = os.Rename(destTmpPath, destFilePath)
-						if checkErr(err) {
-							_ = os.Remove(destTmpPath)
+				if action.Type == ActionFail {
+					checkErr(action, action.Err)
+					continue
+				}
 
-							continue
+				recordSynced := func() {
+					plan.manifest.Set(action.SourceRelative, ManifestEntry{
+						Size:               action.sourceInfo.Size(),
+						ModTime:            action.sourceInfo.ModTime(),
+						OutputPath:         action.DestRelative,
+						ProfileFingerprint: plan.profFingerprint,
+						IsRemuxed:          action.remux,
+					})
+
+					// Remove the previous output if it had a different path, such as a different extension
+					if action.ReplacesRelative != "" {
+						err := os.Remove(filepath.Join(destPath, action.ReplacesRelative))
+						if err != nil && !errors.Is(err, os.ErrNotExist) {
+							s.Events.Emit(Event{
+								Type:   EventError,
+								Sync:   sync,
+								Action: action,
+								Err:    err,
+							})
+						} else {
+							emit(EventOutdatedRemoved, action)
 						}
-
-						s.Progress.Completed.Add(1)
 					}
 				}
 
-				if shouldCopyRaw {
-					destFilePath := filepath.Join(destPath, fileRelative)
-					destTmpPath := destFilePath + ".tmp"
+				if action.Type == ActionSkip {
+					recordSynced()
+					s.Progress.Completed.Add(1)
+					emit(EventFileSkipped, action)
+					continue
+				}
 
-					// Check if it already exists
-					_, err := os.Stat(destFilePath)
-					if err == nil {
-						println(s.Locale.Tr("sync.path-already-exists", fileRelative))
-						s.Progress.Completed.Add(1)
-						continue
+				err := syncFile(action)
+				for retry := uint(1); err != nil && retry <= retryPolicy.MaxRetries && ctx.Err() == nil; retry++ {
+					class, ok := classifyError(err)
+					if !ok || !retryPolicy.IsRetryable(class) {
+						break
 					}
 
-					println(s.Locale.Tr("sync.copying", fileRelative))
+					s.Events.Emit(Event{
+						Type:   EventFileRetrying,
+						Sync:   sync,
+						Action: action,
+						Err:    err,
+						Retry:  retry,
+					})
 
-					// Simply copy the file
-					osSrcFile, err := os.Open(srcFilePathFull)
-					if checkErr(err) {
-						_ = os.Remove(destTmpPath)
-						_ = osSrcFile.Close()
-						continue
-					}
-					osDestFile, err := os.Create(destTmpPath)
-					if checkErr(err) {
-						_ = os.Remove(destTmpPath)
-						_ = osSrcFile.Close()
-						_ = osDestFile.Close()
-						continue
-					}
-					_, err = io.Copy(osDestFile, osSrcFile)
-					if checkErr(err) {
-						_ = os.Remove(destTmpPath)
-						_ = osSrcFile.Close()
-						_ = osDestFile.Close()
-						continue
+					if waitBackoff(ctx, retryPolicy.GetBackoff(retry)) != nil || s.pause.Wait(ctx) != nil {
+						break
 					}
 
-					_ = osDestFile.Close()
-					_ = osSrcFile.Close()
-
-					// Successfully copied, rename the tmp file
-					err = os.Rename(destTmpPath, destFilePath)
-					if checkErr(err) {
-						_ = os.Remove(destTmpPath)
-						continue
-					}
+					err = syncFile(action)
+				}
+				if checkErr(action, err) {
+					continue
+				}
 
-					s.Progress.Completed.Add(1)
+				recordSynced()
+				s.Progress.Completed.Add(1)
+				if action.Type == ActionTranscode {
+					emit(EventTranscodeFinished, action)
+				} else {
+					emit(EventCopyFinished, action)
 				}
 			}
 		}()
 	}
 
-	// Walk source directory
-	err := filepath.WalkDir(srcPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+	// Wait for all processes to finish
+	for i := 0; i < concurrency; i++ {
+		<-doneChan
+	}
 
-		if d.IsDir() {
-			return nil
+	// Forget about source files that no longer exist.
+	// Partial plans don't cover every source file, so they can't tell which ones are gone.
+	if !plan.partial {
+		seen := make(map[string]struct{}, len(plan.Actions))
+		for _, action := range plan.Actions {
+			if action.SourceRelative != "" {
+				seen[action.SourceRelative] = struct{}{}
+			}
 		}
-
-		s.Progress.Total.Add(1)
-		fileChan <- path
-
-		return nil
-	})
-	close(fileChan)
-	if checkErr(err) {
-		return
+		plan.manifest.Retain(seen)
+	}
+	err := plan.manifest.Save(plan.manifestPath)
+	if err != nil {
+		s.Events.Emit(Event{
+			Type: EventError,
+			Sync: sync,
+			Err:  err,
+		})
 	}
 
-	// Wait for all processes to finish
-	for i := 0; i < concurrency; i++ {
-		<-doneChan
+	// Don't leave the next sync paused
+	s.pause.Resume()
+
+	status := SyncStatusCompleted
+	if ctx.Err() != nil {
+		status = SyncStatusCancelled
 	}
 
-	logOut <- s.Locale.Tr(
-		"sync.done",
-		strconv.Itoa(int(s.Progress.Total.Load())),
-		strconv.Itoa(int(s.Progress.Completed.Load())),
-		strconv.Itoa(int(s.Progress.Failed.Load())),
-	)
+	s.Progress.Status.Store(int32(status))
 	s.Progress.Sync.Store(nil)
+	s.Events.Emit(Event{
+		Type: EventSyncFinished,
+		Sync: sync,
+		Totals: SyncTotals{
+			Status:    status,
+			Total:     s.Progress.Total.Load(),
+			Completed: s.Progress.Completed.Load(),
+			Failed:    s.Progress.Failed.Load(),
+		},
+	})
+
+	return status
 }
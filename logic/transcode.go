@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"github.com/termermc/your-loss-sync/config"
+	"strconv"
+	"strings"
+)
+
+// defaultBitDepth is the bit depth of lossless outputs whose source bit depth is unknown, such as lossy sources.
+const defaultBitDepth = 16
+
+// outputBitDepth returns the bit depth that a source with the specified audio stream is encoded with by a profile.
+// Returns 0 for formats that don't support bit depths.
+func outputBitDepth(prof *config.OutputProfile, audio sourceAudio) uint {
+	depth := audio.BitDepth
+	if depth == 0 {
+		depth = defaultBitDepth
+	}
+	if prof.MaxBitDepth > 0 && depth > prof.MaxBitDepth {
+		depth = prof.MaxBitDepth
+	}
+
+	return prof.OutputFormat.GetBitDepth(depth)
+}
+
+// outputChannels returns the number of channels that a source with the specified audio stream is encoded with by a
+// profile.
+// Returns 0 if the source's number of channels is unknown and the profile does not limit it.
+func outputChannels(prof *config.OutputProfile, audio sourceAudio) uint {
+	// Sources whose number of channels is unknown are downmixed as well, so that they are guaranteed to fit
+	if maxChannels := prof.ChannelPolicy.MaxChannels(); maxChannels > 0 && (audio.Channels == 0 || audio.Channels > maxChannels) {
+		return maxChannels
+	}
+
+	// Stereo is the most widely supported layout, so fall back to it rather than the format's maximum
+	if prof.OutputFormat.MaxChannels > 0 && audio.Channels > prof.OutputFormat.MaxChannels {
+		return 2
+	}
+
+	return audio.Channels
+}
+
+// remuxArgs returns the FFmpeg arguments that copy the audio of a remuxed ActionCopy action's source file to the
+// specified path without re-encoding it, rewriting its tags and artwork.
+// The artwork, whether it is the folder image or the source file's own, is encoded according to the action's profile,
+// which is also used for the ID3v2 version of containers with ID3 tags.
+func remuxArgs(action *Action, destPath string) []string {
+	prof := action.Profile
+
+	args := []string{"-i", action.SourcePath}
+	switch {
+	case action.ArtworkPath != "":
+		// The source file has no artwork, so the folder image is the only video stream
+		args = append(args, artworkArgs(action, "0")...)
+		args = append(args, "-c", "copy")
+		args = append(args, prof.ArtworkEncodeArgs()...)
+	case action.hasArtwork:
+		args = append(args, "-map", "0", "-c", "copy")
+		args = append(args, prof.ArtworkEncodeArgs()...)
+	default:
+		// Only the audio is mapped, which drops any artwork that the output doesn't keep
+		args = append(args, "-map", "0:a", "-c", "copy")
+	}
+
+	format, _ := getTagFormat(action.audio)
+	args = append(args, metadataArgs(action, format, prof.GetId3Version())...)
+
+	return append(args, destPath, "-y")
+}
+
+// transcodeArgs returns the FFmpeg arguments that transcode the source file of an ActionTranscode action to the
+// specified path.
+func transcodeArgs(action *Action, destPath string) []string {
+	prof := action.Profile
+	format := prof.OutputFormat
+
+	args := []string{"-i", action.SourcePath}
+	args = append(args, artworkArgs(action, "0:a:0")...)
+	args = append(args, prof.ArtworkArgs()...)
+	args = append(args, "-c:a", action.Encoder)
+
+	modeOpts := prof.GetEncodingModeOptions()
+	args = append(args, modeOpts.Args...)
+	if modeOpts.UsesQuality() {
+		args = append(args, modeOpts.QualityOption, strconv.FormatFloat(prof.Quality, 'f', -1, 64))
+	} else if !format.IsLossless {
+		args = append(args, "-b:a", strconv.Itoa(int(prof.Bitrate)))
+	}
+
+	// Resampling and reducing the bit depth are both done by the resampler so that it can apply dither
+	var resampleOpts []string
+	if prof.MaxSampleRate > 0 && action.audio.SampleRate > prof.MaxSampleRate {
+		resampleOpts = append(resampleOpts, "osr="+strconv.Itoa(int(prof.MaxSampleRate)))
+	}
+
+	if bitDepth := outputBitDepth(prof, action.audio); bitDepth > 0 {
+		sampleFmt := format.SampleFormats[bitDepth]
+
+		isReduced := action.audio.BitDepth == 0 || bitDepth < action.audio.BitDepth
+		if isReduced && bitDepth <= config.MaxDitherBitDepth && prof.UsesDither() {
+			resampleOpts = append(resampleOpts, "osf="+sampleFmt, "dither_method="+prof.DitherMethod)
+		}
+
+		args = append(args, "-sample_fmt", sampleFmt)
+
+		// Sample formats are wider than the bit depth they hold, so tell the encoder how many bits are used
+		if _, hasEncoder := format.BitDepthEncoders[bitDepth]; !hasEncoder {
+			args = append(args, "-bits_per_raw_sample", strconv.Itoa(int(bitDepth)))
+		}
+	}
+
+	// Downmixing is done by the resampler as well, which uses the standard downmix coefficients.
+	// The mix is normalized so that it doesn't clip.
+	if channels := outputChannels(prof, action.audio); channels > 0 && channels != action.audio.Channels {
+		resampleOpts = append(resampleOpts, "rematrix_maxval=1")
+		args = append(args, "-ac", strconv.Itoa(int(channels)))
+	}
+
+	// Extra arguments come last so that they can override any of the options above
+	args = append(args, metadataArgs(action, format, prof.GetId3Version())...)
+	args = append(args, prof.ExtraArgs...)
+
+	// The profile's filters run first so that resampling, dithering and downmixing apply to their output
+	var filters []string
+	if prof.AudioFilter != "" {
+		filters = append(filters, prof.AudioFilter)
+	}
+	if len(resampleOpts) > 0 {
+		filters = append(filters, "aresample="+strings.Join(resampleOpts, ":"))
+	}
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+
+	return append(args, destPath, "-y")
+}
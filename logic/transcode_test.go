@@ -0,0 +1,240 @@
+package logic
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/termermc/your-loss-sync/config"
+)
+
+// argValue returns the value of the last occurrence of an FFmpeg option in args.
+// Returns false if the option is not in args.
+func argValue(args []string, opt string) (string, bool) {
+	for i := len(args) - 2; i >= 0; i-- {
+		if args[i] == opt {
+			return args[i+1], true
+		}
+	}
+
+	return "", false
+}
+
+func TestTranscodeArgsLimits(t *testing.T) {
+	flac := config.SupportedOutputFormats[1]
+	wav := config.SupportedOutputFormats[2]
+
+	flac16 := &config.OutputProfile{OutputFormat: flac, EncodingMode: config.EncodingCompression, Quality: 5, MaxSampleRate: 48000, MaxBitDepth: 16, DitherMethod: "triangular"}
+	flac24 := &config.OutputProfile{OutputFormat: flac, EncodingMode: config.EncodingCompression, Quality: 5, MaxBitDepth: 24, DitherMethod: "triangular"}
+	wavSource := &config.OutputProfile{OutputFormat: wav}
+	wav24 := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[9]}
+
+	hiRes := sourceAudio{Codec: "flac", IsLossless: true, SampleRate: 96000, BitDepth: 24, Channels: 2}
+	cd := sourceAudio{Codec: "flac", IsLossless: true, SampleRate: 44100, BitDepth: 16, Channels: 2}
+	float := sourceAudio{Codec: "pcm_f32le", IsLossless: true, SampleRate: 44100, BitDepth: 32, Channels: 2}
+
+	tests := []struct {
+		name          string
+		prof          *config.OutputProfile
+		audio         sourceAudio
+		wantSampleFmt string
+		wantRawBits   string
+		wantFilter    string
+	}{
+		{"resampled and dithered to 16 bits", flac16, hiRes, "s16", "16", "aresample=osr=48000:osf=s16:dither_method=triangular"},
+		{"within the limits", flac16, cd, "s16", "16", ""},
+		{"reduced to 24 bits without dither", flac24, float, "s32", "24", ""},
+		{"PCM keeps the source's bit depth", wavSource, hiRes, "s32", "", ""},
+		{"PCM of CD audio", wavSource, cd, "s16", "", ""},
+		{"24-bit PCM pads CD audio", wav24, cd, "s32", "24", ""},
+		{"24-bit PCM reduces floats", wav24, float, "s32", "24", ""},
+	}
+
+	for _, test := range tests {
+		action := &Action{
+			Type:       ActionTranscode,
+			SourcePath: "/music/song.flac",
+			Encoder:    test.prof.OutputFormat.GetEncoder(outputBitDepth(test.prof, test.audio)),
+			Profile:    test.prof,
+			audio:      test.audio,
+		}
+		args := transcodeArgs(action, "/out/song")
+
+		if got, _ := argValue(args, "-sample_fmt"); got != test.wantSampleFmt {
+			t.Errorf("%s: -sample_fmt = %q, want %q", test.name, got, test.wantSampleFmt)
+		}
+		if got, _ := argValue(args, "-bits_per_raw_sample"); got != test.wantRawBits {
+			t.Errorf("%s: -bits_per_raw_sample = %q, want %q", test.name, got, test.wantRawBits)
+		}
+		if got, _ := argValue(args, "-af"); got != test.wantFilter {
+			t.Errorf("%s: -af = %q, want %q", test.name, got, test.wantFilter)
+		}
+		if args[len(args)-2] != "/out/song" || args[len(args)-1] != "-y" {
+			t.Errorf("%s: arguments don't end with the output: %q", test.name, args)
+		}
+	}
+}
+
+func TestTranscodeArgsEncoding(t *testing.T) {
+	mp3 := config.SupportedOutputFormats[0]
+	flac := config.SupportedOutputFormats[1]
+
+	tests := []struct {
+		name        string
+		prof        *config.OutputProfile
+		wantBitrate string
+		wantOption  string
+		wantQuality string
+	}{
+		{"constant bitrate", &config.OutputProfile{OutputFormat: mp3, Bitrate: 320000}, "320000", "", ""},
+		{"variable bitrate", &config.OutputProfile{OutputFormat: mp3, EncodingMode: config.EncodingVbr, Quality: 2}, "", "-q:a", "2"},
+		{"compression level", &config.OutputProfile{OutputFormat: flac, EncodingMode: config.EncodingCompression, Quality: 8}, "", "-compression_level", "8"},
+	}
+
+	for _, test := range tests {
+		action := &Action{
+			Type:       ActionTranscode,
+			SourcePath: "/music/song.flac",
+			Encoder:    test.prof.OutputFormat.FfmpegEncoder,
+			Profile:    test.prof,
+			audio:      sourceAudio{Codec: "flac", IsLossless: true, SampleRate: 44100, BitDepth: 16, Channels: 2},
+		}
+		args := transcodeArgs(action, "/out/song")
+
+		if got, _ := argValue(args, "-c:a"); got != test.prof.OutputFormat.FfmpegEncoder {
+			t.Errorf("%s: -c:a = %q, want %q", test.name, got, test.prof.OutputFormat.FfmpegEncoder)
+		}
+		if got, _ := argValue(args, "-b:a"); got != test.wantBitrate {
+			t.Errorf("%s: -b:a = %q, want %q", test.name, got, test.wantBitrate)
+		}
+		if test.wantOption != "" {
+			if got, _ := argValue(args, test.wantOption); got != test.wantQuality {
+				t.Errorf("%s: %s = %q, want %q", test.name, test.wantOption, got, test.wantQuality)
+			}
+		}
+	}
+}
+
+func TestOutputChannels(t *testing.T) {
+	mp3 := config.SupportedOutputFormats[0]
+	flac := config.SupportedOutputFormats[1]
+
+	tests := []struct {
+		name     string
+		prof     *config.OutputProfile
+		channels uint
+		want     uint
+	}{
+		{"preserved", &config.OutputProfile{OutputFormat: flac}, 6, 6},
+		{"preserved when unknown", &config.OutputProfile{OutputFormat: flac}, 0, 0},
+		{"beyond the format's maximum", &config.OutputProfile{OutputFormat: mp3}, 6, 2},
+		{"within the format's maximum", &config.OutputProfile{OutputFormat: mp3}, 1, 1},
+		{"downmixed to stereo", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyStereo}, 6, 2},
+		{"stereo policy keeps mono", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyStereo}, 1, 1},
+		{"stereo policy when unknown", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyStereo}, 0, 2},
+		{"downmixed to mono", &config.OutputProfile{OutputFormat: mp3, ChannelPolicy: config.ChannelPolicyMono}, 2, 1},
+		{"mono policy when unknown", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyMono}, 0, 1},
+	}
+
+	for _, test := range tests {
+		if got := outputChannels(test.prof, sourceAudio{Channels: test.channels}); got != test.want {
+			t.Errorf("%s: outputChannels() = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTranscodeArgsDownmix(t *testing.T) {
+	flac := config.SupportedOutputFormats[1]
+
+	tests := []struct {
+		name         string
+		prof         *config.OutputProfile
+		channels     uint
+		wantChannels string
+		wantFilter   string
+	}{
+		{"surround downmixed to stereo", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyStereo}, 6, "2", "aresample=rematrix_maxval=1"},
+		{"stereo is left alone", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyStereo}, 2, "", ""},
+		{"surround is preserved", &config.OutputProfile{OutputFormat: flac}, 6, "", ""},
+		{"filters run before the downmix", &config.OutputProfile{OutputFormat: flac, ChannelPolicy: config.ChannelPolicyMono, AudioFilter: "volume=0.5"}, 2, "1", "volume=0.5,aresample=rematrix_maxval=1"},
+	}
+
+	for _, test := range tests {
+		action := &Action{
+			Type:       ActionTranscode,
+			SourcePath: "/music/song.flac",
+			Encoder:    flac.FfmpegEncoder,
+			Profile:    test.prof,
+			audio:      sourceAudio{Codec: "flac", IsLossless: true, Channels: test.channels},
+		}
+		args := transcodeArgs(action, "/out/song")
+
+		if got, _ := argValue(args, "-ac"); got != test.wantChannels {
+			t.Errorf("%s: -ac = %q, want %q", test.name, got, test.wantChannels)
+		}
+		if got, _ := argValue(args, "-af"); got != test.wantFilter {
+			t.Errorf("%s: -af = %q, want %q", test.name, got, test.wantFilter)
+		}
+	}
+}
+
+func TestRemuxArgs(t *testing.T) {
+	flacAudio := sourceAudio{Codec: "flac", IsLossless: true, Containers: []string{"flac"}}
+	tags := []tag{{Key: "title", Value: "Song"}}
+	copyArt := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[1]}
+	jpegArt := &config.OutputProfile{OutputFormat: config.SupportedOutputFormats[1], ArtworkPolicy: config.ArtworkJpeg, ArtworkQuality: 100}
+
+	tests := []struct {
+		name   string
+		action *Action
+		want   []string
+	}{
+		{
+			"tags only",
+			&Action{SourcePath: "/music/song.flac", Profile: copyArt, audio: flacAudio, tags: tags},
+			[]string{"-i", "/music/song.flac", "-map", "0:a", "-c", "copy", "-map_metadata", "-1", "-metadata", "TITLE=Song", "/out/song", "-y"},
+		},
+		{
+			"embedded artwork is re-encoded",
+			&Action{SourcePath: "/music/song.flac", Profile: jpegArt, audio: flacAudio, tags: tags, hasArtwork: true},
+			[]string{
+				"-i", "/music/song.flac", "-map", "0", "-c", "copy", "-c:v", "mjpeg", "-q:v", "2", "-pix_fmt", "yuvj420p",
+				"-map_metadata", "-1", "-map_metadata:s:v", "0:s:v", "-metadata", "TITLE=Song", "/out/song", "-y",
+			},
+		},
+		{
+			"folder image is embedded",
+			&Action{SourcePath: "/music/song.flac", ArtworkPath: "/music/cover.jpg", Profile: copyArt, audio: flacAudio, tags: tags},
+			[]string{
+				"-i", "/music/song.flac", "-i", "/music/cover.jpg", "-map", "0", "-map", "1:v:0", "-disposition:v:0", "attached_pic",
+				"-metadata:s:v:0", "title=Album cover", "-metadata:s:v:0", "comment=Cover (front)", "-c", "copy", "-c:v", "copy",
+				"-map_metadata", "-1", "-metadata", "TITLE=Song", "/out/song", "-y",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if got := remuxArgs(test.action, "/out/song"); !slices.Equal(got, test.want) {
+			t.Errorf("%s: remuxArgs() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGetTagFormat(t *testing.T) {
+	tests := []struct {
+		audio    sourceAudio
+		wantName string
+	}{
+		{sourceAudio{Codec: "flac", Containers: []string{"flac"}}, "FLAC"},
+		{sourceAudio{Codec: "aac", Containers: []string{"mov", "mp4", "m4a"}}, "AAC"},
+		{sourceAudio{Codec: "pcm_s24le", Containers: []string{"wav"}}, "WAV"},
+		{sourceAudio{Codec: "flac", Containers: []string{"ogg"}}, ""},
+		{sourceAudio{Codec: "wmav2", Containers: []string{"asf"}}, ""},
+	}
+
+	for _, test := range tests {
+		format, ok := getTagFormat(test.audio)
+		if ok != (test.wantName != "") || format.Name != test.wantName {
+			t.Errorf("getTagFormat(%+v) = %q, %v, want %q", test.audio, format.Name, ok, test.wantName)
+		}
+	}
+}